@@ -0,0 +1,171 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// APIURL is the base URL for Slack Web API methods. Unlike incoming
+// webhooks, these calls need a bot or user token.
+var APIURL = "https://slack.com/api/"
+
+type APIResponse struct {
+	Ok      bool   `json:"ok"`
+	Ts      string `json:"ts"`
+	Channel string `json:"channel"`
+	Error   string `json:"error"`
+	// UploadURL and FileID are returned by files.getUploadURLExternal.
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// SendAPI posts payload via chat.postMessage and returns the message ts,
+// which can later be used to update or thread on the message.
+func SendAPI(token string, payload Payload) (string, []error) {
+	var response APIResponse
+	if errs := apiCall(token, "chat.postMessage", payload, &response); len(errs) > 0 {
+		return "", errs
+	}
+
+	return response.Ts, nil
+}
+
+// SendEphemeral posts payload via chat.postEphemeral so that only user sees
+// it in channel. This needs a bot token; incoming webhooks can't post
+// ephemeral messages.
+func SendEphemeral(token, channel, user string, payload Payload) []error {
+	payload.Channel = channel
+	payload.User = user
+
+	var response APIResponse
+	return apiCall(token, "chat.postEphemeral", payload, &response)
+}
+
+type updateRequest struct {
+	Payload
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+}
+
+// UpdateMessageAPI replaces the message identified by channel and ts, the
+// values returned from SendAPI, with payload via chat.update.
+func UpdateMessageAPI(token, channel, ts string, payload Payload) []error {
+	var response APIResponse
+	return apiCall(token, "chat.update", updateRequest{Payload: payload, Channel: channel, Ts: ts}, &response)
+}
+
+type reactionRequest struct {
+	Channel   string `json:"channel"`
+	Timestamp string `json:"timestamp"`
+	Name      string `json:"name"`
+}
+
+// AddReaction adds the emoji name (without colons) to the message identified
+// by channel and timestamp, the ts returned from SendAPI.
+func AddReaction(token, channel, timestamp, name string) []error {
+	var response APIResponse
+	return apiCall(token, "reactions.add", reactionRequest{Channel: channel, Timestamp: timestamp, Name: name}, &response)
+}
+
+// RemoveReaction removes the emoji name added by AddReaction.
+func RemoveReaction(token, channel, timestamp, name string) []error {
+	var response APIResponse
+	return apiCall(token, "reactions.remove", reactionRequest{Channel: channel, Timestamp: timestamp, Name: name}, &response)
+}
+
+type uploadedFile struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+type completeUploadRequest struct {
+	Files     []uploadedFile `json:"files"`
+	ChannelID string         `json:"channel_id,omitempty"`
+}
+
+// UploadFile uploads content as filename and shares it in channel, returning
+// the file ID. It uses the files.getUploadURLExternal and
+// files.completeUploadExternal flow that replaces files.upload.
+func UploadFile(token, channel, filename string, content []byte) (string, []error) {
+	if sendingDisabled() {
+		return "", nil
+	}
+
+	form := url.Values{}
+	form.Set("filename", filename)
+	form.Set("length", strconv.Itoa(len(content)))
+
+	var response APIResponse
+	if errs := apiRequest(token, "files.getUploadURLExternal", "application/x-www-form-urlencoded", []byte(form.Encode()), &response); len(errs) > 0 {
+		return "", errs
+	}
+
+	opts := sendOptions{header: http.Header{}}
+	opts.header.Set("Content-Type", "application/octet-stream")
+	if _, errs := post(response.UploadURL, content, opts); len(errs) > 0 {
+		return "", errs
+	}
+
+	fileID := response.FileID
+	request := completeUploadRequest{
+		Files:     []uploadedFile{{ID: fileID, Title: filename}},
+		ChannelID: channel,
+	}
+	if errs := apiCall(token, "files.completeUploadExternal", request, &APIResponse{}); len(errs) > 0 {
+		return "", errs
+	}
+
+	return fileID, nil
+}
+
+func apiCall(token string, method string, request interface{}, response *APIResponse) []error {
+	if sendingDisabled() {
+		return nil
+	}
+
+	requestJson, err := json.Marshal(request)
+	if err != nil {
+		return []error{err}
+	}
+
+	return apiRequest(token, method, "application/json; charset=utf-8", requestJson, response)
+}
+
+// apiRequest posts body to method and checks the response is ok.
+func apiRequest(token string, method string, contentType string, body []byte, response *APIResponse) []error {
+	opts := sendOptions{header: http.Header{}}
+	opts.header.Set("Authorization", "Bearer "+token)
+	opts.header.Set("Content-Type", contentType)
+
+	result, errs := post(APIURL+method, body, opts)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if err := decodeResponse(result, response); err != nil {
+		return []error{err}
+	}
+
+	if !response.Ok {
+		return []error{fmt.Errorf("Error calling %s: %s", method, response.Error)}
+	}
+
+	return nil
+}
+
+// decodeResponse unmarshals a JSON response body into v, including the start
+// of the body in the error when it isn't valid JSON.
+func decodeResponse(result sendResult, v interface{}) error {
+	if err := json.Unmarshal(result.body, v); err != nil {
+		body := result.body
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return fmt.Errorf("Error decoding response (status %v): %w: %q", result.statusCode, err, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package slack
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestSendAPI(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	defer func(apiURL string) { APIURL = apiURL }(APIURL)
+	APIURL = "http://test.com/api/"
+
+	gock.New("http://test.com").
+		Post("/api/chat.postMessage").
+		MatchHeader("Authorization", "Bearer xoxb-test").
+		Reply(200).
+		JSON(`{"ok":true,"channel":"C123","ts":"1503435956.000247"}`)
+	gock.New("http://test.com").
+		Post("/api/chat.postMessage").
+		Reply(200).
+		JSON(`{"ok":false,"error":"channel_not_found"}`)
+
+	gock.DisableNetworking()
+
+	ts, errs := SendAPI("xoxb-test", Payload{Channel: "C123", Text: "Hello"})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if ts != "1503435956.000247" {
+		t.Errorf("Expected ts 1503435956.000247, got %q", ts)
+	}
+
+	if _, errs := SendAPI("xoxb-other", Payload{Channel: "C404", Text: "Hello"}); len(errs) == 0 {
+		t.Error("Expected an error for ok:false response")
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	defer func(apiURL string) { APIURL = apiURL }(APIURL)
+	APIURL = "http://test.com/api/"
+
+	gock.New("http://test.com").
+		Post("/api/files.getUploadURLExternal").
+		MatchHeader("Authorization", "Bearer xoxb-test").
+		BodyString("filename=report.txt&length=5").
+		Reply(200).
+		JSON(`{"ok":true,"upload_url":"http://files.test.com/upload/v1/abc","file_id":"F123"}`)
+	gock.New("http://files.test.com").
+		Post("/upload/v1/abc").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			// BodyString doesn't match application/octet-stream bodies.
+			body, err := io.ReadAll(req.Body)
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			return string(body) == "hello", err
+		}).
+		Reply(200).
+		BodyString("OK - 5")
+	gock.New("http://test.com").
+		Post("/api/files.completeUploadExternal").
+		BodyString(`{"files":[{"id":"F123","title":"report.txt"}],"channel_id":"C123"}`).
+		Reply(200).
+		JSON(`{"ok":true,"files":[{"id":"F123"}]}`)
+
+	gock.DisableNetworking()
+
+	fileID, errs := UploadFile("xoxb-test", "C123", "report.txt", []byte("hello"))
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if fileID != "F123" {
+		t.Errorf("Expected file ID F123, got %q", fileID)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected all three upload steps to be called")
+	}
+}
+
+func TestAPIMethods(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	defer func(apiURL string) { APIURL = apiURL }(APIURL)
+	APIURL = "http://test.com/api/"
+
+	tests := []struct {
+		method string
+		call   func() []error
+		body   string
+		reply  string
+		// errorName is what Slack answers the second call with.
+		errorName string
+	}{
+		{
+			method:    "reactions.add",
+			call:      func() []error { return AddReaction("xoxb-test", "C123", "1503435956.000247", "thumbsup") },
+			body:      `{"channel":"C123","timestamp":"1503435956.000247","name":"thumbsup"}`,
+			reply:     `{"ok":true}`,
+			errorName: "already_reacted",
+		},
+		{
+			method:    "reactions.remove",
+			call:      func() []error { return RemoveReaction("xoxb-test", "C123", "1503435956.000247", "thumbsup") },
+			body:      `{"channel":"C123","timestamp":"1503435956.000247","name":"thumbsup"}`,
+			reply:     `{"ok":true}`,
+			errorName: "no_reaction",
+		},
+		{
+			method:    "chat.postEphemeral",
+			call:      func() []error { return SendEphemeral("xoxb-test", "C123", "U123", Payload{Text: "Only you"}) },
+			body:      `{"channel":"C123","user":"U123","text":"Only you"}`,
+			reply:     `{"ok":true,"message_ts":"1503435956.000247"}`,
+			errorName: "user_not_in_channel",
+		},
+		{
+			method: "chat.update",
+			call: func() []error {
+				return UpdateMessageAPI("xoxb-test", "C123", "1503435956.000247", Payload{Text: "Updated"})
+			},
+			body:      `{"text":"Updated","channel":"C123","ts":"1503435956.000247"}`,
+			reply:     `{"ok":true,"channel":"C123","ts":"1503435956.000247"}`,
+			errorName: "message_not_found",
+		},
+	}
+	for _, test := range tests {
+		gock.New("http://test.com").
+			Post("/api/"+test.method).
+			MatchHeader("Authorization", "Bearer xoxb-test").
+			BodyString(test.body).
+			Reply(200).
+			JSON(test.reply)
+		gock.New("http://test.com").
+			Post("/api/" + test.method).
+			Reply(200).
+			JSON(`{"ok":false,"error":"` + test.errorName + `"}`)
+
+		gock.DisableNetworking()
+
+		if errs := test.call(); len(errs) > 0 {
+			t.Errorf("%s: unexpected errors: %v", test.method, errs)
+		}
+		if errs := test.call(); len(errs) != 1 || !strings.Contains(errs[0].Error(), test.errorName) {
+			t.Errorf("%s: expected the %s error, got %v", test.method, test.errorName, errs)
+		}
+		if !gock.IsDone() {
+			t.Errorf("%s: expected both requests to be made", test.method)
+		}
+
+		gock.Off()
+	}
+}
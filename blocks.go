@@ -0,0 +1,202 @@
+package slack
+
+import (
+	"encoding/json"
+)
+
+// Block is a Block Kit layout block. Each implementation adds its own "type"
+// when marshaled.
+type Block interface {
+	BlockType() string
+}
+
+type TextObject struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+func PlainText(text string) *TextObject {
+	return &TextObject{Type: "plain_text", Text: text}
+}
+
+// NewBlockAttachment returns an attachment laid out entirely with blocks, which
+// is how Slack recommends keeping a colored bar alongside Block Kit content.
+func NewBlockAttachment(color string, blocks ...Block) Attachment {
+	return Attachment{Color: &color, Blocks: blocks}
+}
+
+// MaxHeaderLength is Slack's limit on the text of a header block.
+const MaxHeaderLength = 150
+
+type HeaderBlock struct {
+	Text    *TextObject `json:"text"`
+	BlockID string      `json:"block_id,omitempty"`
+}
+
+// NewHeaderBlock returns a header block, truncating text to MaxHeaderLength.
+func NewHeaderBlock(text string) HeaderBlock {
+	return HeaderBlock{Text: PlainText(truncateRunes(text, MaxHeaderLength))}
+}
+
+func (HeaderBlock) BlockType() string { return "header" }
+
+func (b HeaderBlock) MarshalJSON() ([]byte, error) {
+	type header HeaderBlock
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		header
+	}{b.BlockType(), header(b)})
+}
+
+// RichTextBlock holds formatted content such as sections and preformatted
+// text, which keeps code and log output intact better than mrkdwn strings.
+type RichTextBlock struct {
+	Elements []RichTextElement `json:"elements"`
+	BlockID  string            `json:"block_id,omitempty"`
+}
+
+func NewRichTextBlock(elements ...RichTextElement) RichTextBlock {
+	return RichTextBlock{Elements: elements}
+}
+
+func (RichTextBlock) BlockType() string { return "rich_text" }
+
+func (b RichTextBlock) MarshalJSON() ([]byte, error) {
+	type richText RichTextBlock
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		richText
+	}{b.BlockType(), richText(b)})
+}
+
+// RichTextElement is a top level element of a RichTextBlock.
+type RichTextElement interface {
+	RichTextElementType() string
+}
+
+// RichText is a run of text or a link inside a rich text element.
+type RichText struct {
+	Type  string         `json:"type"`
+	Text  string         `json:"text,omitempty"`
+	URL   string         `json:"url,omitempty"`
+	Style *RichTextStyle `json:"style,omitempty"`
+}
+
+type RichTextStyle struct {
+	Bold   bool `json:"bold,omitempty"`
+	Italic bool `json:"italic,omitempty"`
+	Strike bool `json:"strike,omitempty"`
+	Code   bool `json:"code,omitempty"`
+}
+
+func RichTextText(text string) RichText {
+	return RichText{Type: "text", Text: text}
+}
+
+func RichTextLink(url, text string) RichText {
+	return RichText{Type: "link", URL: url, Text: text}
+}
+
+type RichTextSection struct {
+	Elements []RichText `json:"elements"`
+}
+
+func NewRichTextSection(elements ...RichText) RichTextSection {
+	return RichTextSection{Elements: elements}
+}
+
+func (RichTextSection) RichTextElementType() string { return "rich_text_section" }
+
+func (e RichTextSection) MarshalJSON() ([]byte, error) {
+	type section RichTextSection
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		section
+	}{e.RichTextElementType(), section(e)})
+}
+
+type RichTextPreformatted struct {
+	Elements []RichText `json:"elements"`
+}
+
+// NewRichTextPreformatted returns a preformatted (code block) element
+// holding text verbatim.
+func NewRichTextPreformatted(text string) RichTextPreformatted {
+	return RichTextPreformatted{Elements: []RichText{RichTextText(text)}}
+}
+
+func (RichTextPreformatted) RichTextElementType() string { return "rich_text_preformatted" }
+
+func (e RichTextPreformatted) MarshalJSON() ([]byte, error) {
+	type preformatted RichTextPreformatted
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		preformatted
+	}{e.RichTextElementType(), preformatted(e)})
+}
+
+// MaxButtonsPerRow is the most buttons ButtonRows puts in one actions block.
+const MaxButtonsPerRow = 5
+
+type ButtonElement struct {
+	Text     *TextObject `json:"text"`
+	ActionID string      `json:"action_id,omitempty"`
+	URL      string      `json:"url,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"`
+}
+
+func NewButton(text, actionID string) ButtonElement {
+	return ButtonElement{Text: PlainText(text), ActionID: actionID}
+}
+
+func (e ButtonElement) MarshalJSON() ([]byte, error) {
+	type button ButtonElement
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		button
+	}{"button", button(e)})
+}
+
+type ActionsBlock struct {
+	Elements []ButtonElement `json:"elements"`
+	BlockID  string          `json:"block_id,omitempty"`
+}
+
+func (ActionsBlock) BlockType() string { return "actions" }
+
+func (b ActionsBlock) MarshalJSON() ([]byte, error) {
+	type actions ActionsBlock
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		actions
+	}{b.BlockType(), actions(b)})
+}
+
+// ButtonRows lays buttons out in as many actions blocks as needed to keep
+// each to MaxButtonsPerRow, instead of Slack rejecting the message.
+func ButtonRows(buttons []ButtonElement) []Block {
+	var rows []Block
+	for start := 0; start < len(buttons); start += MaxButtonsPerRow {
+		end := start + MaxButtonsPerRow
+		if end > len(buttons) {
+			end = len(buttons)
+		}
+		rows = append(rows, ActionsBlock{Elements: append([]ButtonElement(nil), buttons[start:end]...)})
+	}
+	return rows
+}
+
+// truncateRunes shortens s to at most max runes, marking the cut with an
+// ellipsis.
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if max <= 0 {
+		return ""
+	}
+	return string(runes[:max-1]) + "…"
+}
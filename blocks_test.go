@@ -0,0 +1,90 @@
+package slack
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHeaderBlock(t *testing.T) {
+	payload := Payload{
+		Blocks: []Block{NewHeaderBlock("Deploy finished")},
+	}
+
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"blocks":[{"type":"header","text":{"type":"plain_text","text":"Deploy finished"}}]}`
+	if string(payloadJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, payloadJson)
+	}
+
+	header := NewHeaderBlock(strings.Repeat("é", 200))
+	if n := utf8.RuneCountInString(header.Text.Text); n != MaxHeaderLength {
+		t.Errorf("Expected header truncated to %d runes, got %d", MaxHeaderLength, n)
+	}
+}
+
+func TestBlockAttachment(t *testing.T) {
+	attachment := NewBlockAttachment("#36a64f", NewHeaderBlock("Build passed"))
+
+	attachmentJson, err := json.Marshal(attachment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"color":"#36a64f","blocks":[{"type":"header","text":{"type":"plain_text","text":"Build passed"}}]}`
+	if string(attachmentJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, attachmentJson)
+	}
+}
+
+func TestRichTextBlock(t *testing.T) {
+	block := NewRichTextBlock(
+		NewRichTextSection(RichTextText("Build log:")),
+		NewRichTextPreformatted("line 1\nline 2"),
+	)
+
+	blockJson, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"type":"rich_text","elements":[` +
+		`{"type":"rich_text_section","elements":[{"type":"text","text":"Build log:"}]},` +
+		`{"type":"rich_text_preformatted","elements":[{"type":"text","text":"line 1\nline 2"}]}]}`
+	if string(blockJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, blockJson)
+	}
+}
+
+func TestButtonRows(t *testing.T) {
+	var buttons []ButtonElement
+	for i := 0; i < 7; i++ {
+		buttons = append(buttons, NewButton(strings.Repeat("x", i+1), ""))
+	}
+
+	rows := ButtonRows(buttons)
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	if n := len(rows[0].(ActionsBlock).Elements); n != MaxButtonsPerRow {
+		t.Errorf("Expected a full first row, got %d buttons", n)
+	}
+	if n := len(rows[1].(ActionsBlock).Elements); n != 2 {
+		t.Errorf("Expected 2 buttons in the last row, got %d", n)
+	}
+
+	blockJson, err := json.Marshal(ButtonRows([]ButtonElement{NewButton("Approve", "approve")})[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"Approve"},"action_id":"approve"}]}`
+	if string(blockJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, blockJson)
+	}
+}
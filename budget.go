@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// RetryBudgetPerMinute caps the number of retries across all sends, so
+	// a Slack outage doesn't turn into a retry storm. Zero means unlimited.
+	RetryBudgetPerMinute float64
+	// RetryBudgetBurst is how many retries can be saved up. Zero means the
+	// same as RetryBudgetPerMinute.
+	RetryBudgetBurst int
+
+	retryBudgetLock   sync.Mutex
+	retryBudgetTokens float64
+	retryBudgetFilled bool
+	retryBudgetLast   time.Time
+)
+
+var ErrRetryBudgetExhausted = errors.New("Retry budget exhausted")
+
+func takeRetryToken() bool {
+	retryBudgetLock.Lock()
+	defer retryBudgetLock.Unlock()
+
+	if RetryBudgetPerMinute <= 0 {
+		return true
+	}
+
+	burst := float64(RetryBudgetBurst)
+	if burst <= 0 {
+		burst = RetryBudgetPerMinute
+	}
+
+	now := clock.Now()
+	if !retryBudgetFilled {
+		retryBudgetTokens = burst
+		retryBudgetFilled = true
+	} else {
+		retryBudgetTokens += now.Sub(retryBudgetLast).Minutes() * RetryBudgetPerMinute
+		if retryBudgetTokens > burst {
+			retryBudgetTokens = burst
+		}
+	}
+	retryBudgetLast = now
+
+	if retryBudgetTokens < 1 {
+		return false
+	}
+	retryBudgetTokens--
+	return true
+}
+
+var (
+	// MaxPerMinute sheds sends beyond this many in any rolling minute,
+	// failing them with ErrRateLimitedLocally instead of queueing. Zero
+	// means unlimited.
+	MaxPerMinute int
+
+	recentSendsLock sync.Mutex
+	recentSends     []time.Time
+	droppedSends    int64
+)
+
+var ErrRateLimitedLocally = errors.New("Dropped msg, MaxPerMinute exceeded")
+
+// DroppedMessages returns how many sends MaxPerMinute has shed.
+func DroppedMessages() int64 {
+	recentSendsLock.Lock()
+	defer recentSendsLock.Unlock()
+
+	return droppedSends
+}
+
+func allowSend() bool {
+	recentSendsLock.Lock()
+	defer recentSendsLock.Unlock()
+
+	if MaxPerMinute <= 0 {
+		return true
+	}
+
+	now := clock.Now()
+	for len(recentSends) > 0 && now.Sub(recentSends[0]) >= time.Minute {
+		recentSends = recentSends[1:]
+	}
+
+	if len(recentSends) >= MaxPerMinute {
+		droppedSends++
+		return false
+	}
+
+	recentSends = append(recentSends, now)
+	return true
+}
@@ -0,0 +1,74 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+func TestRetryBudgetExhausted(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	RetryBudgetPerMinute, RetryBudgetBurst, retryBudgetFilled = 1, 1, false
+	defer func() { RetryBudgetPerMinute, RetryBudgetBurst, retryBudgetFilled = 0, 0, false }()
+
+	gock.New("http://test.com").
+		Post("/429").
+		Persist().
+		Reply(429)
+
+	gock.DisableNetworking()
+
+	errs := Send("http://test.com/429", "", Payload{Text: "Hello"})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrRetryBudgetExhausted) {
+		t.Fatalf("Expected ErrRetryBudgetExhausted, got %v", errs)
+	}
+	// Only the retry the budget allowed is waited for.
+	if len(fake.sleeps) != 1 {
+		t.Errorf("Expected a single backoff sleep, got %v", fake.sleeps)
+	}
+}
+
+func TestMaxPerMinute(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	MaxPerMinute, recentSends = 2, nil
+	defer func() { MaxPerMinute, recentSends = 0, nil }()
+
+	gock.New("http://test.com").
+		Post("/200").
+		Persist().
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	dropped := DroppedMessages()
+	for i := 0; i < 3; i++ {
+		errs := Send("http://test.com/200", "", Payload{Text: "Hello"})
+		if i < 2 && len(errs) > 0 {
+			t.Fatalf("Unexpected errors: %v", errs)
+		}
+		if i == 2 && (len(errs) != 1 || !errors.Is(errs[0], ErrRateLimitedLocally)) {
+			t.Fatalf("Expected ErrRateLimitedLocally, got %v", errs)
+		}
+	}
+	if DroppedMessages() != dropped+1 {
+		t.Errorf("Expected one dropped message, got %d", DroppedMessages()-dropped)
+	}
+
+	fake.now = fake.now.Add(time.Minute)
+	if errs := Send("http://test.com/200", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Errorf("Expected sends to resume after a minute, got %v", errs)
+	}
+}
@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for the adaptive rate limiting. Tests can swap
+// it with SetClock to observe or skip the backoff sleeps.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// SleepContext is Sleep that returns ctx.Err() as soon as ctx is done,
+	// so cancelling a send interrupts its backoff.
+	SleepContext(ctx context.Context, d time.Duration) error
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(d time.Duration) { SleepFunc(d) }
+
+// SleepContext runs SleepFunc in the background so it can stop waiting for it
+// when ctx is done. An abandoned SleepFunc finishes on its own.
+func (realClock) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	slept := make(chan struct{})
+	go func() {
+		SleepFunc(d)
+		close(slept)
+	}()
+
+	select {
+	case <-slept:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var clock Clock = realClock{}
+
+// SetClock replaces the package clock. Passing nil restores the real clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
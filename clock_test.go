@@ -0,0 +1,394 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Sleep(d)
+	return nil
+}
+
+func TestSendBackoffProgression(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	interval, increment, decrement := StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement
+	defer func() {
+		StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement = interval, increment, decrement
+	}()
+	StatusCodeRetryInterval = 100 * time.Millisecond
+	StatusCodeRetryIntervalIncrement = 100 * time.Millisecond
+	StatusCodeRetryIntervalDecrement = 10 * time.Millisecond
+
+	gock.New("http://test.com").
+		Post("/backoff").
+		Times(2).
+		Reply(429)
+	gock.New("http://test.com").
+		Post("/backoff").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := Send("http://test.com/backoff", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(fake.sleeps) != len(expected) {
+		t.Fatalf("Expected sleeps %v, got %v", expected, fake.sleeps)
+	}
+	for i := range expected {
+		if fake.sleeps[i] != expected[i] {
+			t.Errorf("Expected sleeps %v, got %v", expected, fake.sleeps)
+			break
+		}
+	}
+
+	if StatusCodeRetryInterval != 290*time.Millisecond {
+		t.Errorf("Expected interval to decrement to 290ms, got %v", StatusCodeRetryInterval)
+	}
+}
+
+func TestSendWithDeadline(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	gock.New("http://test.com").
+		Post("/429").
+		Persist().
+		Reply(429)
+
+	gock.DisableNetworking()
+
+	errs := SendWithDeadline("http://test.com/429", Payload{Text: "Hello"}, fake.now.Add(time.Second))
+	if len(errs) != 1 || !errors.Is(errs[0], ErrDeadlineExceeded) {
+		t.Fatalf("Expected ErrDeadlineExceeded, got %v", errs)
+	}
+	if !fake.now.Equal(time.Unix(1, 0)) {
+		t.Errorf("Expected retries to stop at the deadline, stopped at %v", fake.now)
+	}
+}
+
+func TestSendWithDeadlineCutsOffRequest(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(2 * time.Second):
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	errs := SendWithDeadline(server.URL, Payload{Text: "Hello"}, start.Add(100*time.Millisecond))
+	if len(errs) != 1 || !errors.Is(errs[0], ErrDeadlineExceeded) {
+		t.Errorf("Expected ErrDeadlineExceeded, got %v", errs)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to be cut off at the deadline, took %v", elapsed)
+	}
+}
+
+func TestSendWithRetryOptions(t *testing.T) {
+	defer gock.Off()
+	defer SetClock(nil)
+
+	interval, increment := StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement
+	defer func() {
+		StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement = interval, increment
+	}()
+
+	gock.New("http://test.com").
+		Post("/503").
+		Persist().
+		Reply(503)
+
+	gock.DisableNetworking()
+
+	tests := []struct {
+		options  RetryOptions
+		expected error
+		sleeps   int
+	}{
+		{RetryOptions{MaxAttempts: 3, MaxTotalWait: time.Minute}, ErrMaxAttempts, 2},
+		{RetryOptions{MaxAttempts: 10, MaxTotalWait: 250 * time.Millisecond}, ErrMaxTotalWait, 1},
+	}
+	for _, test := range tests {
+		sends := map[string]func() []error{
+			"SendWithRetryOptions": func() []error {
+				return SendWithRetryOptions("http://test.com/503", Payload{Text: "Hello"}, test.options)
+			},
+			"SendWith": func() []error {
+				return SendWith("http://test.com/503", Payload{Text: "Hello"}, WithMaxAttempts(test.options.MaxAttempts), WithMaxTotalWait(test.options.MaxTotalWait))
+			},
+		}
+		for name, send := range sends {
+			fake := &fakeClock{now: time.Unix(0, 0)}
+			SetClock(fake)
+			StatusCodeRetryInterval = 100 * time.Millisecond
+			StatusCodeRetryIntervalIncrement = 100 * time.Millisecond
+
+			errs := send()
+
+			var statusErr *HTTPStatusError
+			if len(errs) != 2 || !errors.Is(errs[0], test.expected) || !errors.As(errs[1], &statusErr) {
+				t.Errorf("%s: expected %v and the status error, got %v", name, test.expected, errs)
+			}
+			if len(fake.sleeps) != test.sleeps {
+				t.Errorf("%s: expected %d sleeps for %+v, got %v", name, test.sleeps, test.options, fake.sleeps)
+			}
+		}
+	}
+}
+
+func TestConcurrentSuccessesDecrementInterval(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	interval, decrement := StatusCodeRetryInterval, StatusCodeRetryIntervalDecrement
+	defer func() {
+		StatusCodeRetryInterval, StatusCodeRetryIntervalDecrement = interval, decrement
+	}()
+	StatusCodeRetryInterval = time.Second
+	StatusCodeRetryIntervalDecrement = 10 * time.Millisecond
+
+	gock.New("http://test.com").
+		Post("/ok").
+		Persist().
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	const sends = 50
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs := Send("http://test.com/ok", "", Payload{Text: "Hello"}); len(errs) > 0 {
+				t.Errorf("Unexpected errors: %v", errs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := CurrentRetryInterval(); got != time.Second-sends*10*time.Millisecond {
+		t.Errorf("Expected every success to decrement the interval, got %v", got)
+	}
+}
+
+func TestSendRetryAfterMs(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	interval, increment, decrement := StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement
+	defer func() {
+		StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement = interval, increment, decrement
+	}()
+	StatusCodeRetryInterval = 100 * time.Millisecond
+	StatusCodeRetryIntervalIncrement = 100 * time.Millisecond
+	StatusCodeRetryIntervalDecrement = 10 * time.Millisecond
+
+	gock.New("http://test.com").
+		Post("/ms").
+		Reply(429).
+		SetHeader("Retry-After", "1").
+		SetHeader("Retry-After-Ms", "150")
+	gock.New("http://test.com").
+		Post("/ms").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := Send("http://test.com/ms", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	// The interval grows to 200ms but is capped by the 150ms from
+	// Retry-After-Ms rather than the 1s Retry-After, then the success
+	// decrements it.
+	if got := CurrentRetryInterval(); got != 140*time.Millisecond {
+		t.Errorf("Expected the interval to follow Retry-After-Ms, got %v", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	SetClock(&fakeClock{now: now})
+	defer SetClock(nil)
+
+	tests := []struct {
+		retryAfter   string
+		retryAfterMs string
+		expected     time.Duration
+	}{
+		{"", "", noRetryAfter},
+		{"2", "", 2 * time.Second},
+		{"2", "150", 150 * time.Millisecond},
+		{"2", "soon", 2 * time.Second},
+		{now.Add(3 * time.Second).Format(http.TimeFormat), "", 3 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), "", 0},
+		{"soon", "", noRetryAfter},
+		{"-1", "", noRetryAfter},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		if test.retryAfter != "" {
+			header.Set("Retry-After", test.retryAfter)
+		}
+		if test.retryAfterMs != "" {
+			header.Set("Retry-After-Ms", test.retryAfterMs)
+		}
+		if got := parseRetryAfter(header); got != test.expected {
+			t.Errorf("parseRetryAfter(%q, %q) = %v, expected %v", test.retryAfter, test.retryAfterMs, got, test.expected)
+		}
+	}
+}
+
+func TestSendRetryAfterDate(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	gock.New("http://test.com").
+		Post("/date").
+		Reply(503).
+		SetHeader("Retry-After", fake.Now().Add(time.Second).Format(http.TimeFormat))
+	gock.New("http://test.com").
+		Post("/date").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := Send("http://test.com/date", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Expected a Retry-After date to be retried, got %v", errs)
+	}
+}
+
+func TestAdjustInterval(t *testing.T) {
+	increment, decrement := StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement
+	defer func() {
+		StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement = increment, decrement
+	}()
+	StatusCodeRetryIntervalIncrement = 100 * time.Millisecond
+	StatusCodeRetryIntervalDecrement = 10 * time.Millisecond
+
+	tests := []struct {
+		current    time.Duration
+		status     int
+		retryAfter time.Duration
+		expected   time.Duration
+	}{
+		{time.Second, 429, noRetryAfter, 1100 * time.Millisecond},
+		{maxRetryInterval, 429, noRetryAfter, maxRetryInterval},
+		{time.Second, 429, 500 * time.Millisecond, 500 * time.Millisecond},
+		{time.Second, 503, 0, 0},
+		{time.Second, 200, noRetryAfter, 990 * time.Millisecond},
+		{5 * time.Millisecond, 200, noRetryAfter, 0},
+		{0, 200, noRetryAfter, 0},
+		{time.Second, 404, noRetryAfter, time.Second},
+	}
+	for _, test := range tests {
+		if got := adjustInterval(test.current, test.status, test.retryAfter); got != test.expected {
+			t.Errorf("adjustInterval(%v, %d, %v) = %v, expected %v", test.current, test.status, test.retryAfter, got, test.expected)
+		}
+	}
+
+	// However the statuses arrive the interval stays within its bounds, and
+	// a run of successes brings it back down to 0.
+	interval := time.Duration(0)
+	statuses := []int{429, 429, 200, 503, 429, 404, 200}
+	for i := 0; i < 50; i++ {
+		statuses = append(statuses, 429)
+	}
+	for _, status := range statuses {
+		interval = adjustInterval(interval, status, noRetryAfter)
+		if interval < 0 || interval > maxRetryInterval {
+			t.Fatalf("Interval %v out of bounds after %d", interval, status)
+		}
+	}
+	if interval != maxRetryInterval {
+		t.Errorf("Expected repeated 429s to converge on %v, got %v", maxRetryInterval, interval)
+	}
+	for i := 0; i < 500; i++ {
+		interval = adjustInterval(interval, 200, noRetryAfter)
+	}
+	if interval != 0 {
+		t.Errorf("Expected successes to converge on 0, got %v", interval)
+	}
+}
+
+func TestSendCancelledWhileSleeping(t *testing.T) {
+	defer gock.Off()
+	defer Reset()
+
+	sleepFunc := SleepFunc
+	defer func() { SleepFunc = sleepFunc }()
+	sleeping := make(chan struct{})
+	SleepFunc = func(time.Duration) {
+		close(sleeping)
+		time.Sleep(time.Minute)
+	}
+
+	gock.New("http://test.com").
+		Post("/503").
+		Persist().
+		Reply(503)
+
+	gock.DisableNetworking()
+
+	go func() {
+		<-sleeping
+		CancelAll()
+	}()
+
+	done := make(chan []error)
+	go func() { done <- Send("http://test.com/503", "", Payload{Text: "Hello"}) }()
+
+	select {
+	case errs := <-done:
+		if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", errs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected CancelAll to interrupt the backoff sleep")
+	}
+}
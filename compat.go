@@ -0,0 +1,49 @@
+package slack
+
+// Compatibility selects which flavour of Slack compatible webhook payloads
+// are marshaled for.
+type Compatibility int
+
+const (
+	SlackCompat Compatibility = iota
+	// MattermostCompat targets Mattermost's Slack compatible incoming
+	// webhooks. Payload.Props is sent, while the Slack only fields below are
+	// dropped as Mattermost either rejects or ignores them:
+	//   - top level blocks, metadata, user, parse, link_names, mrkdwn and
+	//     unfurl_links/unfurl_media
+	//   - attachment blocks, callback_id and url actions, since Mattermost
+	//     actions need an integration rather than a url
+	MattermostCompat
+)
+
+// CompatibilityMode applies to every send.
+var CompatibilityMode = SlackCompat
+
+func adaptPayload(payload Payload) Payload {
+	switch CompatibilityMode {
+	case MattermostCompat:
+		payload.Blocks = nil
+		payload.Metadata = nil
+		payload.User = ""
+		payload.Parse = ""
+		payload.LinkNames = ""
+		payload.Markdown = nil
+		payload.UnfurlLinks = false
+		payload.UnfurlMedia = false
+
+		attachments := make([]Attachment, len(payload.Attachments))
+		for i, attachment := range payload.Attachments {
+			attachment.Blocks = nil
+			attachment.CallbackID = nil
+			attachment.Actions = nil
+			attachments[i] = attachment
+		}
+		if len(attachments) > 0 {
+			payload.Attachments = attachments
+		}
+	default:
+		payload.Props = nil
+	}
+
+	return payload
+}
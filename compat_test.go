@@ -0,0 +1,34 @@
+package slack
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMattermostCompat(t *testing.T) {
+	defer func() { CompatibilityMode = SlackCompat }()
+
+	attachment := Attachment{}
+	attachment.AddAction(Action{Type: "button", Text: "Open", Url: "https://example.com"})
+	payload := Payload{
+		Text:        "Hello",
+		Blocks:      []Block{NewHeaderBlock("Hello")},
+		Attachments: []Attachment{attachment},
+		Props:       map[string]interface{}{"card": "details"},
+	}
+
+	slackJson, _ := json.Marshal(adaptPayload(payload))
+	if expected := `{"text":"Hello","attachments":[{"actions":[{"type":"button","text":"Open","url":"https://example.com"}]}],"blocks":[{"type":"header","text":{"type":"plain_text","text":"Hello"}}]}`; string(slackJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, slackJson)
+	}
+
+	CompatibilityMode = MattermostCompat
+	mattermostJson, _ := json.Marshal(adaptPayload(payload))
+	if expected := `{"text":"Hello","attachments":[{}],"props":{"card":"details"}}`; string(mattermostJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, mattermostJson)
+	}
+
+	if len(payload.Attachments[0].Actions) != 1 {
+		t.Error("Expected the caller's attachments to be left alone")
+	}
+}
@@ -0,0 +1,100 @@
+package slack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	// DedupeWindow is how long SendIdempotent remembers a delivered key.
+	DedupeWindow = 5 * time.Minute
+
+	dedupeLock sync.Mutex
+	dedupeSent = make(map[string]time.Time)
+)
+
+// IdempotencyKey derives a key for SendIdempotent from the destination and
+// the payload's content.
+func IdempotencyKey(webhookUrl string, payload Payload) (string, error) {
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(webhookUrl))
+	hash.Write([]byte{0})
+	hash.Write(payloadJson)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SendIdempotent sends payload unless a send with the same key was delivered
+// within DedupeWindow, in which case it returns nil without sending. Slack
+// webhooks have no idempotency keys of their own, so this only guards against
+// duplicates from this process. A send with the same key still in flight
+// counts as delivered, and so does one whose request failed, e.g. timed out
+// or had its connection reset, since Slack may have posted it anyway. A send
+// that Slack rejected, or that failed before reaching it, frees the key to be
+// retried. An empty key is derived with IdempotencyKey.
+func SendIdempotent(webhookUrl string, key string, payload Payload) []error {
+	if key == "" {
+		var err error
+		if key, err = IdempotencyKey(webhookUrl, payload); err != nil {
+			return []error{err}
+		}
+	}
+
+	if !reserveKey(key) {
+		return nil
+	}
+
+	errs := Send(webhookUrl, "", payload)
+
+	dedupeLock.Lock()
+	if len(errs) == 0 || deliveryUnknown(errs) {
+		dedupeSent[key] = clock.Now()
+	} else {
+		delete(dedupeSent, key)
+	}
+	dedupeLock.Unlock()
+
+	return errs
+}
+
+// reserveKey records key as sent unless it was sent within DedupeWindow,
+// reporting whether the caller should send it. Checking and recording under
+// one lock stops concurrent sends with the same key both going out.
+func reserveKey(key string) bool {
+	dedupeLock.Lock()
+	defer dedupeLock.Unlock()
+
+	now := clock.Now()
+	for sentKey, sentAt := range dedupeSent {
+		if now.Sub(sentAt) >= DedupeWindow {
+			delete(dedupeSent, sentKey)
+		}
+	}
+
+	if _, ok := dedupeSent[key]; ok {
+		return false
+	}
+	dedupeSent[key] = now
+	return true
+}
+
+// deliveryUnknown reports whether errs include a request that failed without
+// an answer from Slack, after which the message may have been posted or not.
+func deliveryUnknown(errs []error) bool {
+	for _, err := range errs {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return true
+		}
+	}
+	return false
+}
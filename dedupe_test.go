@@ -0,0 +1,123 @@
+package slack
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+// resetDedupe forgets every delivered key, now and when the test ends.
+func resetDedupe(t *testing.T) {
+	reset := func() {
+		dedupeLock.Lock()
+		dedupeSent = make(map[string]time.Time)
+		dedupeLock.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestSendIdempotent(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	resetDedupe(t)
+
+	gock.New("http://test.com").
+		Post("/once").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	payload := Payload{Text: "Deploy finished"}
+	for i := 0; i < 2; i++ {
+		if errs := SendIdempotent("http://test.com/once", "", payload); len(errs) > 0 {
+			t.Fatalf("Unexpected errors on send %d: %v", i, errs)
+		}
+	}
+}
+
+func TestSendIdempotentConcurrent(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	resetDedupe(t)
+
+	gock.New("http://test.com").
+		Post("/once").
+		AddMatcher(func(*http.Request, *gock.Request) (bool, error) {
+			// Keep the first send in flight while the others arrive.
+			time.Sleep(20 * time.Millisecond)
+			return true, nil
+		}).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs := SendIdempotent("http://test.com/once", "deploy-42", Payload{Text: "Deploy finished"}); len(errs) > 0 {
+				t.Errorf("Unexpected errors: %v", errs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !gock.IsDone() {
+		t.Error("Expected the payload to be sent once")
+	}
+}
+
+func TestSendIdempotentRetriesAfterFailure(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	resetDedupe(t)
+
+	gock.New("http://test.com").
+		Post("/flaky").
+		Reply(404)
+	gock.New("http://test.com").
+		Post("/flaky").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := SendIdempotent("http://test.com/flaky", "deploy-43", Payload{Text: "Deploy finished"}); len(errs) != 1 {
+		t.Fatalf("Expected the first send to fail, got %v", errs)
+	}
+	if errs := SendIdempotent("http://test.com/flaky", "deploy-43", Payload{Text: "Deploy finished"}); len(errs) > 0 {
+		t.Fatalf("Expected a failed send to be retried, got %v", errs)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected the failed send to be sent again")
+	}
+}
+
+func TestSendIdempotentKeepsKeyAfterFailedRequest(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	resetDedupe(t)
+
+	// Nothing matches the first request, so it fails the way a timeout or
+	// reset connection would, without an answer from Slack.
+	gock.DisableNetworking()
+
+	if errs := SendIdempotent("http://test.com/lost", "deploy-44", Payload{Text: "Deploy finished"}); len(errs) != 1 {
+		t.Fatalf("Expected the first send to fail, got %v", errs)
+	}
+
+	gock.New("http://test.com").
+		Post("/lost").
+		Reply(200)
+
+	if errs := SendIdempotent("http://test.com/lost", "deploy-44", Payload{Text: "Deploy finished"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if gock.IsDone() {
+		t.Error("Expected a send that may have been posted not to be sent again")
+	}
+}
@@ -0,0 +1,120 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffPayloads returns the differences between a and b, one per line as
+// "path: a != b" using JSON field names, e.g.
+// `attachments[0].fields[1].value: "up" != "down"`. It returns "" when they
+// are equal, and is meant for test failure messages.
+func DiffPayloads(a, b Payload) string {
+	var lines []string
+	diffValues(&lines, "", reflect.ValueOf(a), reflect.ValueOf(b))
+	return strings.Join(lines, "\n")
+}
+
+func diffValues(lines *[]string, path string, a, b reflect.Value) {
+	report := func() {
+		*lines = append(*lines, fmt.Sprintf("%s: %s != %s", path, describeValue(a), describeValue(b)))
+	}
+
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			report()
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		report()
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				report()
+			}
+			return
+		}
+		diffValues(lines, path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldPath := path
+			if !field.Anonymous {
+				fieldPath = joinJSONPath(path, jsonFieldName(field))
+			}
+			diffValues(lines, fieldPath, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		n := a.Len()
+		if b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			var ai, bi reflect.Value
+			if i < a.Len() {
+				ai = a.Index(i)
+			}
+			if i < b.Len() {
+				bi = b.Index(i)
+			}
+			diffValues(lines, fmt.Sprintf("%s[%d]", path, i), ai, bi)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, key := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			key := keys[name]
+			diffValues(lines, joinJSONPath(path, name), a.MapIndex(key), b.MapIndex(key))
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			report()
+		}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func joinJSONPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describeValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<missing>"
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "nil"
+	}
+	if encoded, err := json.Marshal(v.Interface()); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
@@ -0,0 +1,24 @@
+package slack
+
+import (
+	"testing"
+)
+
+func TestDiffPayloads(t *testing.T) {
+	a := Payload{Text: "Deploy"}
+	a.Attachments = []Attachment{*(&Attachment{}).AddField(Field{Title: "Status", Value: "up"})}
+	b := Payload{Text: "Deploy", Channel: "#ops"}
+	b.Attachments = []Attachment{*(&Attachment{}).AddField(Field{Title: "Status", Value: "down"})}
+	b.Attachments = append(b.Attachments, Attachment{})
+
+	if diff := DiffPayloads(a, a); diff != "" {
+		t.Errorf("Expected no diff for equal payloads, got %q", diff)
+	}
+
+	expected := `channel: "" != "#ops"
+attachments[0].fields[0].value: "up" != "down"
+attachments[1]: <missing> != {}`
+	if diff := DiffPayloads(a, b); diff != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, diff)
+	}
+}
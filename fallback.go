@@ -0,0 +1,37 @@
+package slack
+
+import (
+	"strings"
+)
+
+// channelErrors are the webhook response bodies that mean the message's
+// channel can't be posted to, rather than the message being at fault.
+var channelErrors = map[string]bool{
+	"channel_not_found":   true,
+	"channel_is_archived": true,
+}
+
+// SendWithFallbackChannels sends payload like Send, and if Slack answers
+// that its channel doesn't exist or is archived, tries each of channels in
+// turn until one succeeds. Any other failure is returned straight away.
+// DeadLetterSink only sees the payload once every channel has failed.
+func SendWithFallbackChannels(webhookUrl string, payload Payload, channels []string) []error {
+	var errs []error
+	for i := 0; i <= len(channels); i++ {
+		if i > 0 {
+			payload.Channel = channels[i-1]
+		}
+
+		var result sendResult
+		result, errs = send(webhookUrl, payload, sendOptions{noDeadLetter: true})
+		if len(errs) == 0 {
+			return nil
+		}
+		if !channelErrors[strings.TrimSpace(string(result.body))] {
+			break
+		}
+	}
+
+	deadLetter(payload, errs)
+	return errs
+}
@@ -0,0 +1,61 @@
+package slack
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldsFromStruct returns a field per exported field of the struct v, or
+// of the struct v points to, in declaration order. The field name is the
+// title unless a `slack:"Title,short"` tag gives another, and short marks
+// it short. Fields tagged `slack:"-"` are skipped. Values are formatted with
+// fmt, so nested structs become a single field rather than being expanded.
+func FieldsFromStruct(v interface{}) []*Field {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []*Field
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		tag := structField.Tag.Get("slack")
+		if tag == "-" {
+			continue
+		}
+
+		title, options, _ := strings.Cut(tag, ",")
+		if title == "" {
+			title = structField.Name
+		}
+
+		fields = append(fields, &Field{
+			Title: title,
+			Value: fieldValue(value.Field(i)),
+			Short: options == "short",
+		})
+	}
+
+	return fields
+}
+
+func fieldValue(value reflect.Value) string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldsFromStruct(t *testing.T) {
+	type build struct {
+		Number int
+	}
+	version := "1.2.3"
+	status := struct {
+		Service string  `slack:"Service name,short"`
+		Version *string `slack:",short"`
+		Build   build
+		Secret  string `slack:"-"`
+		private string
+		Missing *string
+	}{Service: "api", Version: &version, Build: build{Number: 42}, Secret: "hunter2", private: "x"}
+
+	expected := []*Field{
+		{Title: "Service name", Value: "api", Short: true},
+		{Title: "Version", Value: "1.2.3", Short: true},
+		{Title: "Build", Value: "{42}"},
+		{Title: "Missing", Value: ""},
+	}
+	if fields := FieldsFromStruct(&status); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected fields %+v, got %+v", expected, fields)
+	}
+
+	if fields := FieldsFromStruct("not a struct"); fields != nil {
+		t.Errorf("Expected no fields for a non-struct, got %+v", fields)
+	}
+}
+
+func TestFieldsFromMap(t *testing.T) {
+	tests := []struct {
+		m        map[string]string
+		expected []*Field
+	}{
+		{nil, []*Field{}},
+		{map[string]string{"Region": "eu-west-1"}, []*Field{{Title: "Region", Value: "eu-west-1", Short: true}}},
+		{
+			map[string]string{"Version": "1.2.3", "Commit": "3e20564", "Region": "eu-west-1"},
+			[]*Field{
+				{Title: "Commit", Value: "3e20564", Short: true},
+				{Title: "Region", Value: "eu-west-1", Short: true},
+				{Title: "Version", Value: "1.2.3", Short: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		// Map iteration order varies, so repeat to catch unstable output.
+		for i := 0; i < 10; i++ {
+			if fields := FieldsFromMap(test.m); !reflect.DeepEqual(fields, test.expected) {
+				t.Fatalf("Expected fields %+v for %v, got %+v", test.expected, test.m, fields)
+			}
+		}
+	}
+}
+
+func TestSortFieldsByTitle(t *testing.T) {
+	tests := []struct {
+		fields   []*Field
+		expected []*Field
+	}{
+		{nil, nil},
+		{
+			[]*Field{{Title: "Version"}, {Title: "Commit"}, {Title: "Region"}},
+			[]*Field{{Title: "Commit"}, {Title: "Region"}, {Title: "Version"}},
+		},
+		{
+			[]*Field{{Title: "Host", Value: "b"}, {Title: "Check"}, {Title: "Host", Value: "a"}},
+			[]*Field{{Title: "Check"}, {Title: "Host", Value: "b"}, {Title: "Host", Value: "a"}},
+		},
+	}
+	for _, test := range tests {
+		attachment := Attachment{Fields: test.fields}
+		if fields := attachment.SortFieldsByTitle().Fields; !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("Expected fields %+v, got %+v", test.expected, fields)
+		}
+	}
+}
+
+func TestFieldsFromPairs(t *testing.T) {
+	tests := []struct {
+		pairs    [][2]string
+		expected []*Field
+	}{
+		{nil, []*Field{}},
+		{
+			// An odd number of pairs leaves the last field alone on its row.
+			[][2]string{{"Deploy", "api"}, {"Version", "1.2.3"}, {"Commit", "3e20564"}},
+			[]*Field{
+				{Title: "Deploy", Value: "api", Short: true},
+				{Title: "Version", Value: "1.2.3", Short: true},
+				{Title: "Commit", Value: "3e20564", Short: true},
+			},
+		},
+		{[][2]string{{"Empty", ""}}, []*Field{{Title: "Empty", Value: "", Short: true}}},
+	}
+	for _, test := range tests {
+		if fields := FieldsFromPairs(test.pairs); !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("Expected fields %+v for %v, got %+v", test.expected, test.pairs, fields)
+		}
+	}
+}
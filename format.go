@@ -0,0 +1,80 @@
+package slack
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BulletList renders items one per line, each starting with a • bullet.
+func BulletList(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "• " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NumberedList renders items one per line numbered from 1.
+func NumberedList(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = strconv.Itoa(i+1) + ". " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CodeBlock wraps s in a ``` block. Backticks inside s are followed by a zero
+// width space so they can't close the block early.
+func CodeBlock(s string) string {
+	return "```\n" + strings.ReplaceAll(s, "`", "`\u200b") + "\n```"
+}
+
+// InlineCode wraps s in single backticks. Slack has no escape for a backtick
+// inside inline code, so they are replaced with the look-alike ˋ.
+func InlineCode(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "ˋ") + "`"
+}
+
+// Link formats url as a Slack link labelled with text.
+func Link(url, text string) string {
+	return "<" + url + "|" + linkTextEscaper.Replace(text) + ">"
+}
+
+// LinkBare formats url as a Slack link showing the url itself.
+func LinkBare(url string) string {
+	return "<" + url + ">"
+}
+
+// ProgressBar renders percent as a bar width characters wide, e.g.
+// "[#####-----] 50%". percent is clamped to 0-100, and a width of 0 or less
+// leaves out the bar.
+func ProgressBar(percent int, width int) string {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	label := strconv.Itoa(percent) + "%"
+	if width <= 0 {
+		return label
+	}
+
+	filled := percent * width / 100
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "] " + label
+}
+
+// FooterTimeLayout is the layout FormatTimeForFooter uses.
+var FooterTimeLayout = "Jan 2, 2006 15:04 MST"
+
+// FormatTimeForFooter formats t in loc for footer text, for when Slack's own
+// localization of ts isn't wanted. A nil loc means UTC.
+func FormatTimeForFooter(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(FooterTimeLayout)
+}
+
+var linkTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
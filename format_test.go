@@ -0,0 +1,92 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBulletList(t *testing.T) {
+	tests := []struct {
+		items    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"api"}, "• api"},
+		{[]string{"api", "worker", "cron"}, "• api\n• worker\n• cron"},
+	}
+	for _, test := range tests {
+		if list := BulletList(test.items); list != test.expected {
+			t.Errorf("BulletList(%q) = %q, expected %q", test.items, list, test.expected)
+		}
+	}
+}
+
+func TestNumberedList(t *testing.T) {
+	tests := []struct {
+		items    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"build"}, "1. build"},
+		{[]string{"build", "test", "deploy"}, "1. build\n2. test\n3. deploy"},
+	}
+	for _, test := range tests {
+		if list := NumberedList(test.items); list != test.expected {
+			t.Errorf("NumberedList(%q) = %q, expected %q", test.items, list, test.expected)
+		}
+	}
+}
+
+func TestCodeBlock(t *testing.T) {
+	block := CodeBlock("before ``` after")
+
+	inner := strings.TrimSuffix(strings.TrimPrefix(block, "```\n"), "\n```")
+	if strings.Contains(inner, "``") {
+		t.Errorf("Expected embedded backticks to be broken up, got %q", block)
+	}
+
+	if code := InlineCode("a`b"); code != "`aˋb`" {
+		t.Errorf("Unexpected inline code %q", code)
+	}
+}
+
+func TestLink(t *testing.T) {
+	if link := Link("https://example.com", "click <here>"); link != "<https://example.com|click &lt;here&gt;>" {
+		t.Errorf("Unexpected link %q", link)
+	}
+	if link := LinkBare("https://example.com"); link != "<https://example.com>" {
+		t.Errorf("Unexpected link %q", link)
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		percent, width int
+		expected       string
+	}{
+		{50, 10, "[#####-----] 50%"},
+		{0, 4, "[----] 0%"},
+		{150, 4, "[####] 100%"},
+		{-5, 4, "[----] 0%"},
+		{33, 0, "33%"},
+	}
+	for _, test := range tests {
+		if bar := ProgressBar(test.percent, test.width); bar != test.expected {
+			t.Errorf("ProgressBar(%d, %d) = %q, expected %q", test.percent, test.width, bar, test.expected)
+		}
+	}
+}
+
+func TestFormatTimeForFooter(t *testing.T) {
+	at := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+
+	if footer := FormatTimeForFooter(at, nil); footer != "Mar 5, 2024 14:30 UTC" {
+		t.Errorf("Unexpected footer %q", footer)
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	if footer := FormatTimeForFooter(at, tokyo); footer != "Mar 5, 2024 23:30 JST" {
+		t.Errorf("Unexpected footer %q", footer)
+	}
+}
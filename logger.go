@@ -0,0 +1,19 @@
+package slack
+
+import "log"
+
+// Logger is the subset of *log.Logger the package writes to.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var logger Logger = log.Default()
+
+// SetLogger redirects the package's log output. Passing nil restores the
+// standard logger.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = log.Default()
+	}
+	logger = l
+}
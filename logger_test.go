@@ -0,0 +1,42 @@
+package slack
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestPrettyLog(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	t.Setenv("SLACK_GO_WEBHOOK_DEBUG", "true")
+
+	gock.New("http://test.com").
+		Post("/pretty").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	defer func(pretty bool) { PrettyLog = pretty }(PrettyLog)
+	PrettyLog = true
+
+	buffer := &bufferLogger{}
+	SetLogger(buffer)
+	defer SetLogger(nil)
+
+	if errs := Send("http://test.com/pretty", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	output := strings.Join(buffer.lines, "\n")
+	if !strings.Contains(output, "Slack payload:\n{\n  \"text\": \"Hello\"\n}") {
+		t.Errorf("Expected the indented payload in the injected logger, got %q", output)
+	}
+
+	SetLogger(nil)
+	if logger != log.Default() {
+		t.Error("Expected SetLogger(nil) to restore the standard logger")
+	}
+}
@@ -0,0 +1,85 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// OnMarshalError, when set, is called with every payload Send fails to
+// marshal, e.g. to log it before the error is returned.
+var OnMarshalError func(payload Payload, err *PayloadMarshalError)
+
+// PayloadMarshalError is returned when a payload can't be marshaled to JSON.
+// Field is the JSON path of the value that failed, e.g.
+// "metadata.event_payload.callback", when it can be found.
+type PayloadMarshalError struct {
+	Field string
+	Err   error
+}
+
+func (e *PayloadMarshalError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("Error marshaling payload field %s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("Error marshaling payload: %v", e.Err)
+}
+
+func (e *PayloadMarshalError) Unwrap() error {
+	return e.Err
+}
+
+func marshalError(payload Payload, err error) error {
+	marshalErr := &PayloadMarshalError{Field: marshalFailurePath(reflect.ValueOf(payload), ""), Err: err}
+	if hook := OnMarshalError; hook != nil {
+		hook(payload, marshalErr)
+	}
+	return marshalErr
+}
+
+// marshalFailurePath narrows down which part of value fails to marshal by
+// marshaling its parts in turn.
+func marshalFailurePath(value reflect.Value, path string) string {
+	fails := func(v reflect.Value) bool {
+		_, err := json.Marshal(v.Interface())
+		return err != nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			return marshalFailurePath(value.Elem(), path)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if fails(value.Field(i)) {
+				fieldPath := path
+				if !field.Anonymous {
+					fieldPath = joinJSONPath(path, jsonFieldName(field))
+				}
+				return marshalFailurePath(value.Field(i), fieldPath)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if fails(value.Index(i)) {
+				return marshalFailurePath(value.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		for _, key := range keys {
+			if fails(value.MapIndex(key)) {
+				return marshalFailurePath(value.MapIndex(key), joinJSONPath(path, fmt.Sprint(key.Interface())))
+			}
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,80 @@
+package slack
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SendResult describes the delivery of one message to one webhook. Err is
+// nil when the message was delivered.
+type SendResult struct {
+	URL        string
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+	Attempts   int
+}
+
+// SendMulti sends payload to every url concurrently and returns a result for
+// each, in the same order as urls.
+func SendMulti(urls []string, payload Payload) []SendResult {
+	var wg sync.WaitGroup
+	results := make([]SendResult, len(urls))
+
+	for i, webhookUrl := range urls {
+		wg.Add(1)
+		go func(i int, webhookUrl string) {
+			defer wg.Done()
+
+			start := clock.Now()
+			result, errs := send(webhookUrl, payload, sendOptions{})
+			results[i] = SendResult{
+				URL:        webhookUrl,
+				StatusCode: result.statusCode,
+				Err:        errors.Join(errs...),
+				Duration:   clock.Now().Sub(start),
+				Attempts:   result.attempts,
+			}
+		}(i, webhookUrl)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// SendFromURLFile reads newline separated webhook urls from path, skipping
+// blank lines and # comments, and sends payload to each of them. The error
+// return is only set when the file itself can't be read.
+func SendFromURLFile(path string, payload Payload) (map[string][]error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	failures := make(map[string][]error)
+	for _, result := range SendMulti(urls, payload) {
+		if result.Err != nil {
+			failures[result.URL] = append(failures[result.URL], result.Err)
+		}
+	}
+
+	return failures, nil
+}
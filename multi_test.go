@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestSendMulti(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/ok").
+		Reply(200)
+	gock.New("http://test.com").
+		Post("/retry").
+		Reply(429)
+	gock.New("http://test.com").
+		Post("/retry").
+		Reply(200)
+	gock.New("http://test.com").
+		Post("/gone").
+		Reply(404)
+
+	gock.DisableNetworking()
+
+	urls := []string{"http://test.com/ok", "http://test.com/retry", "http://test.com/gone"}
+	results := SendMulti(urls, Payload{Text: "Hello"})
+	if len(results) != len(urls) {
+		t.Fatalf("Expected %d results, got %d", len(urls), len(results))
+	}
+
+	tests := []struct {
+		statusCode int
+		attempts   int
+		failed     bool
+	}{
+		{200, 1, false},
+		{200, 2, false},
+		{404, 1, true},
+	}
+	for i, test := range tests {
+		result := results[i]
+		if result.URL != urls[i] {
+			t.Errorf("Expected result %d for %s, got %s", i, urls[i], result.URL)
+		}
+		if result.StatusCode != test.statusCode || result.Attempts != test.attempts {
+			t.Errorf("Expected status %d after %d attempts for %s, got %d after %d", test.statusCode, test.attempts, urls[i], result.StatusCode, result.Attempts)
+		}
+		var statusErr *HTTPStatusError
+		if test.failed != errors.As(result.Err, &statusErr) {
+			t.Errorf("Unexpected error for %s: %v", urls[i], result.Err)
+		}
+	}
+}
+
+func TestSendFromURLFile(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/ok").
+		Reply(200)
+	gock.New("http://test.com").
+		Post("/gone").
+		Reply(404)
+
+	gock.DisableNetworking()
+
+	path := filepath.Join(t.TempDir(), "webhooks")
+	contents := "# alerts\nhttp://test.com/ok\n\nhttp://test.com/gone\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	failures, err := SendFromURLFile(path, Payload{Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures["http://test.com/gone"] == nil {
+		t.Errorf("Expected only http://test.com/gone to fail, got %v", failures)
+	}
+
+	if _, err := SendFromURLFile(filepath.Join(t.TempDir(), "missing"), Payload{}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
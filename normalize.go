@@ -0,0 +1,114 @@
+package slack
+
+import "strings"
+
+// AutoNormalize makes Send call Payload.Normalize on a copy of every payload
+// and refuse to send it if normalizing fails.
+var AutoNormalize = false
+
+// Normalize tidies payload into something Slack renders predictably, then
+// validates it. It escapes stray &, < and > in text while leaving Slack
+// links and mentions alone, enables mrkdwn for attachments that don't say
+// otherwise, fills in missing fallbacks, and drops fields and actions beyond
+// what Slack shows. The first validation problem is returned; Warnings lists
+// the ones Normalize lets through.
+//
+// Normalize replaces rather than writes through the pointers it changes, so
+// it doesn't touch values shared with other payloads.
+func (payload *Payload) Normalize() error {
+	payload.Text = escapeText(payload.Text)
+
+	for i := range payload.Attachments {
+		payload.Attachments[i].normalize()
+	}
+
+	return payload.Validate()
+}
+
+func (attachment *Attachment) normalize() {
+	attachment.PreText = escapeTextPtr(attachment.PreText)
+	attachment.Text = escapeTextPtr(attachment.Text)
+
+	fields := attachment.Fields
+	if len(fields) > MaxFieldsPerAttachment {
+		fields = fields[:MaxFieldsPerAttachment]
+	}
+	attachment.Fields = make([]*Field, len(fields))
+	for i, field := range fields {
+		normalized := *field
+		normalized.Value = escapeText(normalized.Value)
+		attachment.Fields[i] = &normalized
+	}
+
+	if len(attachment.Actions) > MaxActionsPerAttachment {
+		attachment.Actions = attachment.Actions[:MaxActionsPerAttachment]
+	}
+
+	if attachment.MarkdownIn == nil {
+		attachment.MarkdownIn = &[]string{"pretext", "text", "fields"}
+	}
+
+	if attachment.Fallback == nil || *attachment.Fallback == "" {
+		for _, candidate := range []*string{attachment.Title, attachment.Text, attachment.PreText} {
+			if candidate != nil && *candidate != "" {
+				fallback := *candidate
+				attachment.Fallback = &fallback
+				break
+			}
+		}
+	}
+}
+
+func escapeTextPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	escaped := escapeText(*s)
+	return &escaped
+}
+
+// escapeText escapes &, < and > except where they already form a Slack
+// control sequence such as <https://example.com|link> or an HTML entity.
+func escapeText(s string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			if end := strings.IndexByte(s[i:], ';'); end > 1 && isEntity(s[i+1:i+end]) {
+				out.WriteString(s[i : i+end+1])
+				i += end
+				continue
+			}
+			out.WriteString("&amp;")
+		case '<':
+			if end := strings.IndexAny(s[i+1:], "<>"); end > 0 && s[i+1+end] == '>' && isControlSequence(s[i+1:i+1+end]) {
+				out.WriteString(s[i : i+end+2])
+				i += end + 1
+				continue
+			}
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+
+	return out.String()
+}
+
+// isControlSequence reports whether inner, the text between < and >, looks
+// like a link, mention or command such as https://example.com|label.
+func isControlSequence(inner string) bool {
+	target, _, _ := strings.Cut(inner, "|")
+	return target != "" && !strings.ContainsAny(target, " \t") && !strings.Contains(inner, "\n")
+}
+
+func isEntity(name string) bool {
+	switch name {
+	case "amp", "lt", "gt":
+		return true
+	}
+	return false
+}
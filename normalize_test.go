@@ -0,0 +1,54 @@
+package slack
+
+import "testing"
+
+func TestEscapeText(t *testing.T) {
+	for input, expected := range map[string]string{
+		"a < b && c > d":                     "a &lt; b &amp;&amp; c &gt; d",
+		"see <https://example.com|the docs>": "see <https://example.com|the docs>",
+		"ping <@U024BE7LH> &amp; <!here>":    "ping <@U024BE7LH> &amp; <!here>",
+		"<not a link>":                       "&lt;not a link&gt;",
+	} {
+		if escaped := escapeText(input); escaped != expected {
+			t.Errorf("escapeText(%q) = %q, expected %q", input, escaped, expected)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	title := "Disk usage"
+	value := "90% > threshold"
+	field := &Field{Title: "sda1", Value: value}
+	attachment := Attachment{Title: &title}
+	for i := 0; i <= MaxFieldsPerAttachment; i++ {
+		attachment.Fields = append(attachment.Fields, field)
+	}
+
+	payload := Payload{Attachments: []Attachment{attachment}}
+	if err := payload.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	normalized := payload.Attachments[0]
+	if len(normalized.Fields) != MaxFieldsPerAttachment {
+		t.Errorf("Expected fields capped at %d, got %d", MaxFieldsPerAttachment, len(normalized.Fields))
+	}
+	if normalized.Fields[0].Value != "90% &gt; threshold" {
+		t.Errorf("Expected escaped field value, got %q", normalized.Fields[0].Value)
+	}
+	if field.Value != value {
+		t.Errorf("Expected the original field to be left alone, got %q", field.Value)
+	}
+	if normalized.Fallback == nil || *normalized.Fallback != title {
+		t.Errorf("Expected fallback from title, got %v", normalized.Fallback)
+	}
+	if normalized.MarkdownIn == nil {
+		t.Error("Expected default mrkdwn_in targets")
+	}
+
+	color := "blurple"
+	payload = Payload{Attachments: []Attachment{{Color: &color}}}
+	if err := payload.Normalize(); err == nil {
+		t.Error("Expected an error for an invalid color")
+	}
+}
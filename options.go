@@ -0,0 +1,84 @@
+package slack
+
+import (
+	"context"
+	"time"
+)
+
+// SendOption configures a single SendWith call.
+type SendOption func(opts *sendOptions)
+
+// SendWith sends payload to webhookUrl configured by opts, e.g.
+//
+//	SendWith(webhookUrl, payload, WithProxy(proxy), WithMaxRetries(3), WithTimeout(10*time.Second))
+//
+// Without options it behaves like Send without a proxy.
+func SendWith(webhookUrl string, payload Payload, opts ...SendOption) []error {
+	var options sendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.timeout > 0 {
+		if options.ctx == nil {
+			options.ctx = rootContext()
+		}
+		var cancel context.CancelFunc
+		options.ctx, cancel = context.WithTimeout(options.ctx, options.timeout)
+		defer cancel()
+	}
+
+	_, errs := send(webhookUrl, payload, options)
+	return errs
+}
+
+// WithContext binds the send to ctx, like SendContext.
+func WithContext(ctx context.Context) SendOption {
+	return func(opts *sendOptions) {
+		opts.ctx = ctx
+	}
+}
+
+// WithTimeout gives up on the send, including retries, after d.
+func WithTimeout(d time.Duration) SendOption {
+	return func(opts *sendOptions) {
+		opts.timeout = d
+	}
+}
+
+// WithProxy sends through the proxy at proxy for this call only.
+func WithProxy(proxy string) SendOption {
+	return func(opts *sendOptions) {
+		opts.proxy = proxy
+	}
+}
+
+// WithMaxRetries gives up after n retries, returning ErrMaxAttempts.
+func WithMaxRetries(n int) SendOption {
+	return WithMaxAttempts(n + 1)
+}
+
+// WithMaxAttempts gives up after n attempts, including the first, returning
+// ErrMaxAttempts.
+func WithMaxAttempts(n int) SendOption {
+	return func(opts *sendOptions) {
+		opts.maxAttempts = n
+	}
+}
+
+// WithMaxTotalWait gives up, returning ErrMaxTotalWait, rather than make a
+// retry whose wait would take the time spent sleeping between attempts over
+// d.
+func WithMaxTotalWait(d time.Duration) SendOption {
+	return func(opts *sendOptions) {
+		opts.maxTotalWait = d
+	}
+}
+
+// WithInitialBackoff waits d before the first retry, like
+// SendOptions.InitialBackoff.
+func WithInitialBackoff(d time.Duration) SendOption {
+	return func(opts *sendOptions) {
+		opts.initialBackoff = d
+	}
+}
@@ -0,0 +1,34 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+func TestSendWith(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/503").
+		Persist().
+		Reply(503)
+
+	gock.DisableNetworking()
+
+	errs := SendWith("http://test.com/503", Payload{Text: "Hello"}, WithMaxRetries(2), WithInitialBackoff(time.Millisecond))
+	if len(errs) == 0 || !errors.Is(errs[0], ErrMaxAttempts) {
+		t.Errorf("Expected ErrMaxAttempts, got %v", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs = SendWith("http://test.com/503", Payload{Text: "Hello"}, WithContext(ctx), WithTimeout(time.Minute))
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", errs)
+	}
+}
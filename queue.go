@@ -0,0 +1,160 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type QueueOptions struct {
+	// Size is how many payloads can wait to be sent. Defaults to 100.
+	Size int
+	// DropWhenFull makes Enqueue return ErrQueueFull instead of blocking
+	// while the queue is full.
+	DropWhenFull bool
+	// OnError, when set, is called with each payload that failed to send.
+	OnError func(payload Payload, errs []error)
+}
+
+// QueueStats counts what has happened to a Queue's payloads so far.
+type QueueStats struct {
+	Enqueued int
+	Sent     int
+	// Dropped counts payloads refused with ErrQueueFull.
+	Dropped int
+	Failed  int
+}
+
+var (
+	ErrQueueFull   = errors.New("Queue full, msg dropped")
+	ErrQueueClosed = errors.New("Queue closed")
+)
+
+// Queue decouples producing messages from delivering them: payloads are
+// sent one at a time by a background worker, so Send's adaptive rate limiting
+// paces delivery however fast they are enqueued.
+type Queue struct {
+	webhookUrl string
+	options    QueueOptions
+	payloads   chan Payload
+	done       chan struct{}
+	// closing is closed by Drain to release Enqueue calls blocked on a
+	// full queue, so Drain never waits on them for the lock.
+	closing     chan struct{}
+	closingOnce sync.Once
+	lock        sync.RWMutex
+	closed      bool
+	statsLock   sync.Mutex
+	stats       QueueStats
+}
+
+func NewQueue(webhookUrl string, options QueueOptions) *Queue {
+	if options.Size <= 0 {
+		options.Size = 100
+	}
+
+	queue := &Queue{
+		webhookUrl: webhookUrl,
+		options:    options,
+		payloads:   make(chan Payload, options.Size),
+		done:       make(chan struct{}),
+		closing:    make(chan struct{}),
+	}
+	go queue.run()
+
+	return queue
+}
+
+func (queue *Queue) run() {
+	defer close(queue.done)
+
+	for payload := range queue.payloads {
+		errs := Send(queue.webhookUrl, "", payload)
+		queue.count(func(stats *QueueStats) {
+			if len(errs) > 0 {
+				stats.Failed++
+			} else {
+				stats.Sent++
+			}
+		})
+		if len(errs) > 0 && queue.options.OnError != nil {
+			queue.options.OnError(payload, errs)
+		}
+	}
+}
+
+func (queue *Queue) Enqueue(payload Payload) error {
+	queue.lock.RLock()
+	defer queue.lock.RUnlock()
+
+	if queue.closed {
+		return ErrQueueClosed
+	}
+
+	// Count the payload before handing it off, so the worker can never
+	// count it sent before it's counted enqueued.
+	queue.count(func(stats *QueueStats) { stats.Enqueued++ })
+
+	if queue.options.DropWhenFull {
+		select {
+		case queue.payloads <- payload:
+			return nil
+		default:
+			queue.count(func(stats *QueueStats) {
+				stats.Enqueued--
+				stats.Dropped++
+			})
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case queue.payloads <- payload:
+		return nil
+	case <-queue.closing:
+		queue.count(func(stats *QueueStats) { stats.Enqueued-- })
+		return ErrQueueClosed
+	}
+}
+
+// Depth returns how many payloads are waiting to be sent, a measure of how
+// far delivery has fallen behind.
+func (queue *Queue) Depth() int {
+	return len(queue.payloads)
+}
+
+// Stats returns a snapshot of the queue's counts. Sent and Failed never
+// exceed Enqueued.
+func (queue *Queue) Stats() QueueStats {
+	queue.statsLock.Lock()
+	defer queue.statsLock.Unlock()
+
+	return queue.stats
+}
+
+func (queue *Queue) count(update func(stats *QueueStats)) {
+	queue.statsLock.Lock()
+	defer queue.statsLock.Unlock()
+
+	update(&queue.stats)
+}
+
+// Drain stops accepting payloads and waits for those already queued to be
+// sent, or for ctx to be done.
+func (queue *Queue) Drain(ctx context.Context) error {
+	queue.closingOnce.Do(func() { close(queue.closing) })
+
+	queue.lock.Lock()
+	if !queue.closed {
+		queue.closed = true
+		close(queue.payloads)
+	}
+	queue.lock.Unlock()
+
+	select {
+	case <-queue.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
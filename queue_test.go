@@ -0,0 +1,132 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+func TestQueue(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/queue").
+		Times(3).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	queue := NewQueue("http://test.com/queue", QueueOptions{
+		OnError: func(payload Payload, errs []error) {
+			t.Errorf("Unexpected errors sending %q: %v", payload.Text, errs)
+		},
+	})
+	for _, text := range []string{"one", "two", "three"} {
+		if err := queue.Enqueue(Payload{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := queue.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected every queued payload to be sent")
+	}
+	if stats := queue.Stats(); stats != (QueueStats{Enqueued: 3, Sent: 3}) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if depth := queue.Depth(); depth != 0 {
+		t.Errorf("Expected an empty queue, got depth %d", depth)
+	}
+	if err := queue.Enqueue(Payload{Text: "late"}); err != ErrQueueClosed {
+		t.Errorf("Expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueueDrainReleasesBlockedEnqueue(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	release := make(chan struct{})
+	gock.New("http://test.com").
+		Post("/slow").
+		Persist().
+		AddMatcher(func(*http.Request, *gock.Request) (bool, error) {
+			<-release
+			return true, nil
+		}).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	queue := NewQueue("http://test.com/slow", QueueOptions{Size: 1})
+	// The first payload is taken by the worker, which blocks sending it, and
+	// the second fills the queue.
+	queue.Enqueue(Payload{Text: "one"})
+	for queue.Depth() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queue.Enqueue(Payload{Text: "two"})
+
+	blocked := make(chan error)
+	go func() { blocked <- queue.Enqueue(Payload{Text: "three"}) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := queue.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Drain to give up at its deadline, got %v", err)
+	}
+	if err := <-blocked; err != ErrQueueClosed {
+		t.Errorf("Expected the blocked Enqueue to return ErrQueueClosed, got %v", err)
+	}
+
+	close(release)
+	if err := queue.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := queue.Stats(); stats != (QueueStats{Enqueued: 2, Sent: 2}) {
+		t.Errorf("Expected only the accepted payloads to count, got %+v", stats)
+	}
+}
+
+func TestQueueStatsNeverSentBeforeEnqueued(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/queue").
+		Persist().
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	queue := NewQueue("http://test.com/queue", QueueOptions{Size: 1})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			queue.Enqueue(Payload{Text: "Hello"})
+		}
+	}()
+
+	for {
+		stats := queue.Stats()
+		if stats.Sent+stats.Failed > stats.Enqueued {
+			t.Fatalf("Stats counted a payload sent before it was enqueued: %+v", stats)
+		}
+		select {
+		case <-done:
+			if err := queue.Drain(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			return
+		default:
+		}
+	}
+}
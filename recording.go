@@ -0,0 +1,113 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type RecordingMode int
+
+const (
+	// Record passes requests through and saves each response.
+	Record RecordingMode = iota
+	// Replay serves the saved responses in order without making requests.
+	Replay
+)
+
+// RecordingTransport is an http.RoundTripper that records real Slack
+// responses to Dir as numbered JSON files and replays them, for
+// deterministic tests of e.g. rate limit sequences. Plug it into a client:
+//
+//	HttpClient = &http.Client{Transport: &RecordingTransport{Mode: Replay, Dir: "testdata/429"}}
+type RecordingTransport struct {
+	Mode RecordingMode
+	Dir  string
+	// Transport makes the requests in Record mode, http.DefaultTransport if
+	// nil.
+	Transport http.RoundTripper
+
+	lock  sync.Mutex
+	count int
+}
+
+type recordedResponse struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+func (transport *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport.lock.Lock()
+	transport.count++
+	path := filepath.Join(transport.Dir, fmt.Sprintf("response-%03d.json", transport.count))
+	transport.lock.Unlock()
+
+	if transport.Mode == Replay {
+		return replayResponse(path, req)
+	}
+
+	next := transport.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded, err := json.MarshalIndent(recordedResponse{
+		Method:     req.Method,
+		URL:        redactURL(req.URL),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, recorded, 0o644); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func replayResponse(path string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("No recorded response for %s %s: %w", req.Method, redactURL(req.URL), err)
+	}
+
+	var recorded recordedResponse
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("Invalid recorded response %s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
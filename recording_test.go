@@ -0,0 +1,46 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordingTransport(t *testing.T) {
+	disableSleep(t)
+
+	statuses := []int{http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("ok"))
+	}))
+
+	dir := t.TempDir()
+	original := HttpClient
+	defer func() { HttpClient = original }()
+
+	HttpClient = &http.Client{Transport: &RecordingTransport{Mode: Record, Dir: dir}}
+	if errs := Send(server.URL+"/hook", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors recording: %v", errs)
+	}
+	server.Close()
+
+	replay := &RecordingTransport{Mode: Replay, Dir: dir}
+	HttpClient = &http.Client{Transport: replay}
+	result, errs := send(server.URL+"/hook", Payload{Text: "Hello"}, sendOptions{})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors replaying: %v", errs)
+	}
+	if result.attempts != 2 {
+		t.Errorf("Expected the 429 and retry to be replayed, got %d attempts", result.attempts)
+	}
+
+	if errs := Send(server.URL+"/hook", "", Payload{Text: "Hello"}); len(errs) == 0 {
+		t.Error("Expected an error once the recorded responses run out")
+	}
+}
@@ -0,0 +1,34 @@
+package slack
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	destinations     = make(map[string]string)
+	destinationsLock sync.RWMutex
+)
+
+// Register names a webhook url so call sites can SendTo it without knowing
+// the url. Registering an existing name replaces its url.
+func Register(name, webhookUrl string) {
+	destinationsLock.Lock()
+	defer destinationsLock.Unlock()
+
+	destinations[name] = webhookUrl
+}
+
+// SendTo sends payload to the webhook url registered as name, failing without
+// a request if there is none.
+func SendTo(name string, payload Payload) []error {
+	destinationsLock.RLock()
+	webhookUrl, ok := destinations[name]
+	destinationsLock.RUnlock()
+
+	if !ok {
+		return []error{fmt.Errorf("Unknown webhook destination: %s", name)}
+	}
+
+	return Send(webhookUrl, "", payload)
+}
@@ -0,0 +1,39 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestSendTo(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	defer func() {
+		destinationsLock.Lock()
+		delete(destinations, "alerts")
+		destinationsLock.Unlock()
+	}()
+
+	gock.New("http://test.com").
+		Post("/new").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	errs := SendTo("alerts", Payload{Text: "Hello"})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "Unknown webhook destination: alerts") {
+		t.Errorf("Expected an unknown destination error, got %v", errs)
+	}
+
+	Register("alerts", "http://test.com/old")
+	Register("alerts", "http://test.com/new")
+	if errs := SendTo("alerts", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Expected the send to go to the replacement url, got %v", errs)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected the replacement url to be used")
+	}
+}
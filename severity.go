@@ -0,0 +1,82 @@
+package slack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityColors = map[Severity]string{
+	SeverityInfo:     "#2eb67d",
+	SeverityWarning:  "#ecb22e",
+	SeverityError:    "#f2780c",
+	SeverityCritical: "#e01e5a",
+}
+
+// Color returns the attachment color used for severity.
+func (severity Severity) Color() string {
+	if color, ok := severityColors[severity]; ok {
+		return color
+	}
+	return severityColors[SeverityInfo]
+}
+
+// SetSeverity colors the attachment for severity.
+func (attachment *Attachment) SetSeverity(severity Severity) *Attachment {
+	color := severity.Color()
+	attachment.Color = &color
+	return attachment
+}
+
+// levelSeverities maps common log level names to severities.
+var levelSeverities = map[string]Severity{
+	"debug":    SeverityInfo,
+	"info":     SeverityInfo,
+	"notice":   SeverityInfo,
+	"warn":     SeverityWarning,
+	"warning":  SeverityWarning,
+	"err":      SeverityError,
+	"error":    SeverityError,
+	"crit":     SeverityCritical,
+	"critical": SeverityCritical,
+	"fatal":    SeverityCritical,
+	"panic":    SeverityCritical,
+}
+
+// AttachmentsByLevel returns an attachment per log level in entries, titled
+// with the level, listing its messages and colored by its severity. The most
+// severe levels come first. Unknown levels are treated as info.
+func AttachmentsByLevel(entries map[string][]string) []Attachment {
+	levels := make([]string, 0, len(entries))
+	for level, messages := range entries {
+		if len(messages) > 0 {
+			levels = append(levels, level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		a, b := levelSeverities[strings.ToLower(levels[i])], levelSeverities[strings.ToLower(levels[j])]
+		if a != b {
+			return a > b
+		}
+		return levels[i] < levels[j]
+	})
+
+	attachments := make([]Attachment, len(levels))
+	for i, level := range levels {
+		title := level
+		text := BulletList(entries[level])
+		fallback := fmt.Sprintf("%s: %d messages", level, len(entries[level]))
+		attachments[i] = Attachment{Title: &title, Text: &text, Fallback: &fallback}
+		attachments[i].SetSeverity(levelSeverities[strings.ToLower(level)])
+	}
+	return attachments
+}
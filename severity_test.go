@@ -0,0 +1,33 @@
+package slack
+
+import (
+	"testing"
+)
+
+func TestAttachmentsByLevel(t *testing.T) {
+	attachments := AttachmentsByLevel(map[string][]string{
+		"info":  {"started", "listening"},
+		"ERROR": {"disk full"},
+		"warn":  {"slow query"},
+		"debug": {},
+	})
+
+	if len(attachments) != 3 {
+		t.Fatalf("Expected 3 attachments, got %d", len(attachments))
+	}
+
+	expected := []struct{ title, color string }{
+		{"ERROR", SeverityError.Color()},
+		{"warn", SeverityWarning.Color()},
+		{"info", SeverityInfo.Color()},
+	}
+	for i, attachment := range attachments {
+		if *attachment.Title != expected[i].title || *attachment.Color != expected[i].color {
+			t.Errorf("Attachment %d: expected %s in %s, got %s in %s", i, expected[i].title, expected[i].color, *attachment.Title, *attachment.Color)
+		}
+	}
+
+	if text := *attachments[2].Text; text != BulletList([]string{"started", "listening"}) {
+		t.Errorf("Unexpected text %q", text)
+	}
+}
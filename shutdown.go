@@ -0,0 +1,38 @@
+package slack
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	rootLock            sync.Mutex
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+)
+
+// rootContext is the context of every send not given one of its own.
+func rootContext() context.Context {
+	rootLock.Lock()
+	defer rootLock.Unlock()
+
+	return rootCtx
+}
+
+// CancelAll cancels every in-flight send that wasn't given its own context,
+// for clean shutdown. Such sends fail with context.Canceled from then on,
+// until Reset.
+func CancelAll() {
+	rootLock.Lock()
+	defer rootLock.Unlock()
+
+	rootCancel()
+}
+
+// Reset lets sends run again after CancelAll. Sends already in flight are
+// left alone.
+func Reset() {
+	rootLock.Lock()
+	defer rootLock.Unlock()
+
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+}
@@ -0,0 +1,44 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestCancelAll(t *testing.T) {
+	defer gock.Off()
+	defer Reset()
+
+	gock.New("http://test.com").
+		Post("/ok").
+		Persist().
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	CancelAll()
+	if errs := Send("http://test.com/ok", "", Payload{Text: "Hello"}); len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("Expected context.Canceled after CancelAll, got %v", errs)
+	}
+	if errs := SendContext(context.Background(), "http://test.com/ok", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Errorf("Expected sends with their own context to run, got %v", errs)
+	}
+
+	Reset()
+	if errs := Send("http://test.com/ok", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Errorf("Expected sends to run after Reset, got %v", errs)
+	}
+}
+
+func TestResetLeavesInFlightSends(t *testing.T) {
+	defer Reset()
+
+	inFlight := rootContext()
+	Reset()
+	if err := inFlight.Err(); err != nil {
+		t.Errorf("Expected Reset not to cancel in-flight sends, got %v", err)
+	}
+}
@@ -2,15 +2,21 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 type Field struct {
@@ -20,31 +26,40 @@ type Field struct {
 }
 
 type Action struct {
-	Type  string `json:"type"`
-	Text  string `json:"text"`
-	Url   string `json:"url"`
-	Style string `json:"style"`
+	Type    string        `json:"type"`
+	Text    string        `json:"text"`
+	Url     string        `json:"url,omitempty"`
+	Style   string        `json:"style,omitempty"`
+	Confirm *ConfirmField `json:"confirm,omitempty"`
+}
+
+type ConfirmField struct {
+	Title       string `json:"title,omitempty"`
+	Text        string `json:"text"`
+	OkText      string `json:"ok_text,omitempty"`
+	DismissText string `json:"dismiss_text,omitempty"`
 }
 
 type Attachment struct {
-	Fallback     *string   `json:"fallback"`
-	Color        *string   `json:"color"`
-	PreText      *string   `json:"pretext"`
-	AuthorName   *string   `json:"author_name"`
-	AuthorLink   *string   `json:"author_link"`
-	AuthorIcon   *string   `json:"author_icon"`
-	Title        *string   `json:"title"`
-	TitleLink    *string   `json:"title_link"`
-	Text         *string   `json:"text"`
-	ImageUrl     *string   `json:"image_url"`
-	Fields       []*Field  `json:"fields"`
-	Footer       *string   `json:"footer"`
-	FooterIcon   *string   `json:"footer_icon"`
-	Timestamp    *int64    `json:"ts"`
-	MarkdownIn   *[]string `json:"mrkdwn_in"`
-	Actions      []*Action `json:"actions"`
-	CallbackID   *string   `json:"callback_id"`
-	ThumbnailUrl *string   `json:"thumb_url"`
+	Fallback     *string   `json:"fallback,omitempty"`
+	Color        *string   `json:"color,omitempty"`
+	PreText      *string   `json:"pretext,omitempty"`
+	AuthorName   *string   `json:"author_name,omitempty"`
+	AuthorLink   *string   `json:"author_link,omitempty"`
+	AuthorIcon   *string   `json:"author_icon,omitempty"`
+	Title        *string   `json:"title,omitempty"`
+	TitleLink    *string   `json:"title_link,omitempty"`
+	Text         *string   `json:"text,omitempty"`
+	ImageUrl     *string   `json:"image_url,omitempty"`
+	Fields       []*Field  `json:"fields,omitempty"`
+	Footer       *string   `json:"footer,omitempty"`
+	FooterIcon   *string   `json:"footer_icon,omitempty"`
+	Timestamp    *int64    `json:"ts,omitempty"`
+	MarkdownIn   *[]string `json:"mrkdwn_in,omitempty"`
+	Actions      []*Action `json:"actions,omitempty"`
+	CallbackID   *string   `json:"callback_id,omitempty"`
+	ThumbnailUrl *string   `json:"thumb_url,omitempty"`
+	Blocks       []Block   `json:"blocks,omitempty"`
 }
 
 type Payload struct {
@@ -53,12 +68,60 @@ type Payload struct {
 	IconUrl     string       `json:"icon_url,omitempty"`
 	IconEmoji   string       `json:"icon_emoji,omitempty"`
 	Channel     string       `json:"channel,omitempty"`
+	User        string       `json:"user,omitempty"`
+	ThreadTs    string       `json:"thread_ts,omitempty"`
 	Text        string       `json:"text,omitempty"`
 	LinkNames   string       `json:"link_names,omitempty"`
 	Attachments []Attachment `json:"attachments,omitempty"`
+	Blocks      []Block      `json:"blocks,omitempty"`
+	Metadata    *Metadata    `json:"metadata,omitempty"`
 	UnfurlLinks bool         `json:"unfurl_links,omitempty"`
 	UnfurlMedia bool         `json:"unfurl_media,omitempty"`
-	Markdown    bool         `json:"mrkdwn,omitempty"`
+	Markdown    *bool        `json:"mrkdwn,omitempty"`
+	// Props is only sent in MattermostCompat mode.
+	Props map[string]interface{} `json:"props,omitempty"`
+	// DefaultColor is given by Send to attachments without a Color of their
+	// own. It isn't sent itself.
+	DefaultColor *string `json:"-"`
+}
+
+// SetChannel sets the channel override, prefixing bare channel names with #.
+// Names already starting with # or @ and conversation IDs are kept as is.
+func (payload *Payload) SetChannel(name string) {
+	if name != "" && !strings.HasPrefix(name, "#") && !strings.HasPrefix(name, "@") && !isConversationID(name) {
+		name = "#" + name
+	}
+	payload.Channel = name
+}
+
+// isConversationID reports whether name looks like a channel, group or DM
+// ID such as C024BE91L.
+func isConversationID(name string) bool {
+	if len(name) < 2 || !strings.ContainsRune("CGD", rune(name[0])) {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Metadata is attached to a message for apps subscribed to their own
+// message metadata events.
+type Metadata struct {
+	EventType    string                 `json:"event_type"`
+	EventPayload map[string]interface{} `json:"event_payload"`
+}
+
+// SortAttachments orders the attachments by less, keeping the relative order
+// of equal ones, so payloads built from concurrent sources render the same
+// way every time.
+func (payload *Payload) SortAttachments(less func(a, b Attachment) bool) {
+	sort.SliceStable(payload.Attachments, func(i, j int) bool {
+		return less(payload.Attachments[i], payload.Attachments[j])
+	})
 }
 
 func (attachment *Attachment) AddField(field Field) *Attachment {
@@ -66,23 +129,276 @@ func (attachment *Attachment) AddField(field Field) *Attachment {
 	return attachment
 }
 
+// AddFieldIf adds field only when cond is true.
+func (attachment *Attachment) AddFieldIf(cond bool, field Field) *Attachment {
+	if cond {
+		attachment.AddField(field)
+	}
+	return attachment
+}
+
+// AddFieldIfNotEmpty adds a short field unless value is "".
+func (attachment *Attachment) AddFieldIfNotEmpty(title, value string) *Attachment {
+	return attachment.AddFieldIf(value != "", Field{Title: title, Value: value, Short: true})
+}
+
+func (attachment *Attachment) AddFields(fields ...*Field) *Attachment {
+	attachment.Fields = append(attachment.Fields, fields...)
+	return attachment
+}
+
+func (attachment *Attachment) SortFieldsByTitle() *Attachment {
+	sort.SliceStable(attachment.Fields, func(i, j int) bool {
+		return attachment.Fields[i].Title < attachment.Fields[j].Title
+	})
+	return attachment
+}
+
+// FieldsFromMap returns short fields sorted by key so the rendered layout is
+// the same on every send.
+func FieldsFromMap(m map[string]string) []*Field {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]*Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, &Field{Title: key, Value: m[key], Short: true})
+	}
+
+	return fields
+}
+
+// FieldsFromPairs returns short fields in the order given, for when the
+// display order matters.
+func FieldsFromPairs(pairs [][2]string) []*Field {
+	fields := make([]*Field, 0, len(pairs))
+	for _, pair := range pairs {
+		fields = append(fields, &Field{Title: pair[0], Value: pair[1], Short: true})
+	}
+
+	return fields
+}
+
 func (attachment *Attachment) AddAction(action Action) *Attachment {
 	attachment.Actions = append(attachment.Actions, &action)
 	return attachment
 }
 
+// MarkdownInFields enables mrkdwn formatting in field values, which Slack
+// otherwise renders literally.
+func (attachment *Attachment) MarkdownInFields() *Attachment {
+	if attachment.MarkdownIn == nil {
+		attachment.MarkdownIn = &[]string{}
+	}
+	for _, target := range *attachment.MarkdownIn {
+		if target == "fields" {
+			return attachment
+		}
+	}
+	*attachment.MarkdownIn = append(*attachment.MarkdownIn, "fields")
+	return attachment
+}
+
+// SetAuthor sets the author name, link and icon, leaving any passed as ""
+// unset.
+func (attachment *Attachment) SetAuthor(name, link, iconURL string) *Attachment {
+	if name != "" {
+		attachment.AuthorName = &name
+	}
+	if link != "" {
+		attachment.AuthorLink = &link
+	}
+	if iconURL != "" {
+		attachment.AuthorIcon = &iconURL
+	}
+	return attachment
+}
+
+// MaxAttachmentTextLength is the most characters of attachment text Slack
+// shows.
+const MaxAttachmentTextLength = 8000
+
+// TruncateTextWithLink shortens text longer than maxLen runes to fit, ending
+// it with "… (truncated)" and, when fullURL isn't "", a link to the full
+// content.
+func (attachment *Attachment) TruncateTextWithLink(maxLen int, fullURL string) *Attachment {
+	if attachment.Text == nil || utf8.RuneCountInString(*attachment.Text) <= maxLen {
+		return attachment
+	}
+
+	suffix := "… (truncated)"
+	if fullURL != "" {
+		suffix = "… (truncated, " + Link(fullURL, "full text") + ")"
+	}
+
+	keep := maxLen - utf8.RuneCountInString(suffix)
+	var text string
+	if keep > 0 {
+		text = string([]rune(*attachment.Text)[:keep]) + suffix
+	} else {
+		text = truncateRunes(*attachment.Text, maxLen)
+	}
+	attachment.Text = &text
+	return attachment
+}
+
+// SetFooterIcon sets the footer icon. Slack only shows footer_icon when
+// footer text is also set, so an empty footer is filled with a zero width
+// space to make the icon appear on its own.
+func (attachment *Attachment) SetFooterIcon(url string) *Attachment {
+	attachment.FooterIcon = &url
+	if attachment.Footer == nil || *attachment.Footer == "" {
+		footer := "\u200b"
+		attachment.Footer = &footer
+	}
+	return attachment
+}
+
+// WithoutTimestamp clears ts so Slack doesn't render a time in the footer.
+func (attachment *Attachment) WithoutTimestamp() *Attachment {
+	attachment.Timestamp = nil
+	return attachment
+}
+
+// SetRelativeTime sets ts to d before now, which Slack renders as e.g.
+// "5 minutes ago" for recent times.
+func (attachment *Attachment) SetRelativeTime(d time.Duration) *Attachment {
+	ts := clock.Now().Add(-d).Unix()
+	attachment.Timestamp = &ts
+	return attachment
+}
+
+// AttachStackTrace returns a danger colored attachment showing trace as a
+// code block.
+func AttachStackTrace(trace string) Attachment {
+	color := "danger"
+	title := "Stack trace"
+	text := CodeBlock(trace)
+	return Attachment{
+		Fallback:   &title,
+		Color:      &color,
+		Title:      &title,
+		Text:       &text,
+		MarkdownIn: &[]string{"text"},
+	}
+}
+
+// NewImageAttachment returns an attachment showing the image at imageURL
+// with a caption.
+func NewImageAttachment(imageURL, title, text string) Attachment {
+	fallback := title
+	if fallback == "" {
+		fallback = imageURL
+	}
+	attachment := Attachment{Fallback: &fallback, ImageUrl: &imageURL}
+	if title != "" {
+		attachment.Title = &title
+	}
+	if text != "" {
+		attachment.Text = &text
+	}
+	return attachment
+}
+
+// AttachmentsWithAlternatingColors returns an attachment per item, colored
+// colorA and colorB in turn so neighbouring items are easy to tell apart.
+func AttachmentsWithAlternatingColors(items []string, colorA, colorB string) []Attachment {
+	attachments := make([]Attachment, len(items))
+	for i, item := range items {
+		text := item
+		color := colorA
+		if i%2 == 1 {
+			color = colorB
+		}
+		attachments[i] = Attachment{Color: &color, Text: &text, Fallback: &text}
+	}
+	return attachments
+}
+
+func NewConfirm(title, text string) *ConfirmField {
+	return &ConfirmField{Title: title, Text: text}
+}
+
+func (confirm *ConfirmField) OK(label string) *ConfirmField {
+	confirm.OkText = label
+	return confirm
+}
+
+func (confirm *ConfirmField) Dismiss(label string) *ConfirmField {
+	confirm.DismissText = label
+	return confirm
+}
+
 var (
 	// Private
-	statusCodeMap        = make(map[int]int)
+	statusCodeMap        = map[string]map[int]int{"": {}}
 	statusCodeLock       sync.Mutex
 	statusCodeTicker     *time.Ticker
 	statusCodeTickerDone = make(chan bool)
+	retryIntervalLock    sync.Mutex
+	inFlightSends        int
+	inFlightLock         sync.Mutex
+	slotFreed            = make(chan struct{})
 	HttpClient           = &http.Client{}
 	// Public
 	StatusCodeTickerInterval         = time.Hour
 	StatusCodeRetryInterval          = time.Millisecond * 100
 	StatusCodeRetryIntervalIncrement = time.Millisecond * 100
 	StatusCodeRetryIntervalDecrement = time.Millisecond * 1
+	// MaxConcurrentSends caps the number of requests in flight across all
+	// callers. Zero means unlimited.
+	MaxConcurrentSends = 0
+	TraceHeader        = "X-Trace-Id"
+	// MaxPayloadBytes is the largest marshaled payload Send will post. Zero
+	// disables the check.
+	MaxPayloadBytes = 40000
+	// SleepFunc is used for every backoff sleep. Tests can replace it with a
+	// no-op or a fake clock.
+	SleepFunc = time.Sleep
+	// PrettyLog logs every outgoing payload as indented JSON when
+	// SLACK_GO_WEBHOOK_DEBUG is set. Off by default as payloads may hold
+	// sensitive content.
+	PrettyLog = false
+	// ValidateURL makes Send check the webhook URL with ValidateWebhookURL
+	// before making a request.
+	ValidateURL = false
+	// DefaultChannel, DefaultUsername and DefaultIconEmoji fill in the
+	// matching payload fields when they are empty. DefaultIconEmoji is not
+	// used when the payload sets IconUrl.
+	DefaultChannel   = ""
+	DefaultUsername  = ""
+	DefaultIconEmoji = ""
+	// AutoColor makes Send give attachments without a color one from
+	// AutoColorPalette, in turn, so multi-attachment messages are easier to
+	// read. Attachments with a color keep it.
+	AutoColor        = false
+	AutoColorPalette = []string{"#1264a3", "#2eb67d", "#ecb22e", "#e01e5a", "#36c5f0", "#9b59b6"}
+	// BeforeRetry, when set, is called before each retry with the payload
+	// about to be resent, the attempt number and the status that caused the
+	// retry. Changes it makes to the payload are sent, e.g. dropping
+	// attachments after a failure.
+	BeforeRetry func(payload *Payload, attempt int, lastStatus int)
+	// DeadLetterSink, when set, is given the payload and error of every send
+	// that fails once it is ready to go, e.g. a network error, a cancelled
+	// context, a permanent 4xx or exhausted retries, so it can be kept
+	// instead of lost. Sends refused before they are posted, by the local
+	// rate limit, an invalid URL or a payload that fails to normalize,
+	// marshal or fit MaxPayloadBytes, never reach it.
+	DeadLetterSink func(payload Payload, err error)
+	// RetryableStatuses are the response codes Send backs off and retries
+	// on. Any other status of 400 or above fails immediately.
+	RetryableStatuses = map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+	}
+	// TraceIDFromContext, when set, supplies the trace ID for SendWithTrace
+	// calls that don't pass one, e.g. from an OpenTelemetry span context.
+	TraceIDFromContext func(ctx context.Context) string
 )
 
 func Init() {
@@ -121,77 +437,764 @@ func MaxDuration(vars ...time.Duration) time.Duration {
 	return max
 }
 
+// Limiter is satisfied by *rate.Limiter from golang.org/x/time/rate.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+type sendOptions struct {
+	ctx       context.Context
+	proxy     string
+	limiter   Limiter
+	header    http.Header
+	basicAuth *url.Userinfo
+	// shouldRetry replaces the adaptive 429 handling when set.
+	shouldRetry func(statusCode int, attempt int) (bool, time.Duration)
+	deadline    time.Time
+	category    string
+	// initialBackoff replaces the adaptive interval before the first retry.
+	initialBackoff time.Duration
+	// beforeRetry returns the body to send for a retry.
+	beforeRetry func(attempt int, lastStatus int) ([]byte, error)
+	// verbose logs each request and response in full.
+	verbose bool
+	// maxAttempts and maxTotalWait bound retrying when non-zero.
+	maxAttempts  int
+	maxTotalWait time.Duration
+	// timeout bounds the whole send when set through SendWith.
+	timeout time.Duration
+	// noDeadLetter leaves calling DeadLetterSink to the caller.
+	noDeadLetter bool
+	// prepared skips preparePayload for payloads it has already been run on.
+	prepared bool
+	// pooled, when set, holds payloadJson so requests can read it without
+	// a copy.
+	pooled *pooledPayload
+}
+
+// SendOptions tunes a single SendContextWithOptions call.
+type SendOptions struct {
+	// InitialBackoff is how long to wait before the first retry, for callers
+	// who already know Slack is rate limiting them. Later retries use the
+	// adaptive interval. Zero uses the adaptive interval throughout.
+	InitialBackoff time.Duration
+}
+
+// RetryOptions bounds the retrying of a single SendWithRetryOptions call.
+// Zero leaves a bound off.
+type RetryOptions struct {
+	// MaxAttempts is the most requests made, including the first.
+	MaxAttempts int
+	// MaxTotalWait is the most time spent sleeping between attempts. A retry
+	// whose wait would take it over is not made.
+	MaxTotalWait time.Duration
+}
+
+// Send sends payload to webhookUrl, through proxy unless it is "".
+//
+// Deprecated: Use SendWith, which takes per call options, e.g.
+// SendWith(webhookUrl, payload, WithProxy(proxy)).
 func Send(webhookUrl string, proxy string, payload Payload) []error {
+	return SendWith(webhookUrl, payload, WithProxy(proxy))
+}
+
+// WebhookBaseURL is the incoming webhook URL WebhookURL appends tokens to.
+var WebhookBaseURL = "https://hooks.slack.com/services/"
+
+// WebhookURL returns the incoming webhook URL for token, the T000/B000/XXXX
+// part after /services/, so the secret can be configured apart from the URL.
+func WebhookURL(token string) string {
+	return WebhookBaseURL + strings.TrimPrefix(token, "/")
+}
 
-	payloadJson, err := json.Marshal(payload)
+// SendWithToken is Send to WebhookURL(token).
+func SendWithToken(token string, payload Payload) []error {
+	_, errs := send(WebhookURL(token), payload, sendOptions{})
+	return errs
+}
+
+// SendAttachments sends a message made up only of attachments.
+func SendAttachments(webhookUrl string, attachments ...Attachment) []error {
+	_, errs := send(webhookUrl, Payload{Attachments: attachments}, sendOptions{})
+	return errs
+}
+
+// SendContext is Send bound to ctx. Cancelling ctx aborts an in-flight
+// request as well as any further retries, so a per call timeout is just
+// context.WithTimeout, independent of HttpClient.Timeout. A nil ctx uses the
+// package context cancelled by CancelAll.
+func SendContext(ctx context.Context, webhookUrl string, proxy string, payload Payload) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{ctx: ctx, proxy: proxy})
+	return errs
+}
+
+// SendContextWithOptions is SendContext tuned by options.
+func SendContextWithOptions(ctx context.Context, webhookUrl string, payload Payload, options SendOptions) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{ctx: ctx, initialBackoff: options.InitialBackoff})
+	return errs
+}
+
+// SendWithRetryOptions is Send that gives up after options.MaxAttempts
+// attempts or options.MaxTotalWait of waiting, whichever comes first,
+// returning ErrMaxAttempts or ErrMaxTotalWait along with the last status
+// error. It is the same as SendWith with WithMaxAttempts and
+// WithMaxTotalWait.
+func SendWithRetryOptions(webhookUrl string, payload Payload, options RetryOptions) []error {
+	return SendWith(webhookUrl, payload, WithMaxAttempts(options.MaxAttempts), WithMaxTotalWait(options.MaxTotalWait))
+}
+
+// SendWithLimiter waits on limiter before every attempt, including retries,
+// and gives up if ctx is cancelled while waiting.
+func SendWithLimiter(ctx context.Context, limiter Limiter, webhookUrl string, payload Payload) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{ctx: ctx, limiter: limiter})
+	return errs
+}
+
+func SendWithTrace(ctx context.Context, webhookUrl string, payload Payload, traceID string) []error {
+	if traceID == "" && TraceIDFromContext != nil {
+		traceID = TraceIDFromContext(ctx)
+	}
+
+	opts := sendOptions{ctx: ctx}
+	if traceID != "" {
+		opts.header = http.Header{}
+		opts.header.Set(TraceHeader, traceID)
+	}
+
+	_, errs := send(webhookUrl, payload, opts)
+	return errs
+}
+
+var (
+	ErrPayloadTooLarge  = errors.New("Payload too large")
+	ErrDeadlineExceeded = errors.New("Deadline exceeded before msg was sent")
+	ErrMaxAttempts      = errors.New("Maximum attempts reached")
+	ErrMaxTotalWait     = errors.New("Maximum total retry wait reached")
+	// ErrUnexpectedResponse is returned when a webhook responds with a
+	// success status but a body other than "ok".
+	ErrUnexpectedResponse = errors.New("Unexpected webhook response")
+)
+
+// HTTPStatusError is returned when Slack responds with a non-retryable error
+// status. RequestID holds Slack's x-slack-req-id header, which Slack support
+// asks for when investigating dropped messages.
+type HTTPStatusError struct {
+	StatusCode int
+	RequestID  string
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("Error sending msg. Status: %v (request id: %s)", e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("Error sending msg. Status: %v", e.StatusCode)
+}
+
+func newHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	return &HTTPStatusError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Slack-Req-Id")}
+}
+
+type sendResult struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+	attempts   int
+	// rateLimited is whether any attempt got a 429.
+	rateLimited bool
+}
+
+// SendResponse describes how a SendWithResponse call went.
+type SendResponse struct {
+	StatusCode int
+	// WasRateLimited is whether Slack answered 429 to any attempt, i.e.
+	// delivery was throttled even if it eventually succeeded.
+	WasRateLimited bool
+	// RetryCount is the number of attempts after the first.
+	RetryCount int
+}
+
+// SendWithResponse is Send that also reports whether the message was
+// throttled on the way, for monitoring delivery health.
+func SendWithResponse(webhookUrl string, payload Payload) (SendResponse, []error) {
+	result, errs := send(webhookUrl, payload, sendOptions{})
+
+	response := SendResponse{StatusCode: result.statusCode, WasRateLimited: result.rateLimited}
+	if result.attempts > 1 {
+		response.RetryCount = result.attempts - 1
+	}
+	return response, errs
+}
+
+// SendWithPolicy lets shouldRetry decide whether an error status is retried
+// and how long to wait first. attempt counts from 1. Returning false fails the
+// send with the response's HTTPStatusError.
+func SendWithPolicy(webhookUrl string, payload Payload, shouldRetry func(statusCode int, attempt int) (bool, time.Duration)) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{shouldRetry: shouldRetry})
+	return errs
+}
+
+// SendWithBasicAuth is Send for Slack compatible endpoints behind HTTP basic
+// auth.
+func SendWithBasicAuth(webhookUrl, username, password string, payload Payload) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{basicAuth: url.UserPassword(username, password)})
+	return errs
+}
+
+// SendWithDeadline retries like Send but gives up with ErrDeadlineExceeded
+// once deadline has passed, however many attempts that allows. A request
+// still in flight at the deadline is cut off.
+func SendWithDeadline(webhookUrl string, payload Payload, deadline time.Time) []error {
+	ctx, cancel := context.WithTimeout(rootContext(), deadline.Sub(clock.Now()))
+	defer cancel()
+
+	_, errs := send(webhookUrl, payload, sendOptions{ctx: ctx, deadline: deadline})
+	for i, err := range errs {
+		if errors.Is(err, context.DeadlineExceeded) {
+			errs[i] = fmt.Errorf("%w: %v", ErrDeadlineExceeded, err)
+		}
+	}
+	return errs
+}
+
+// SendLabeled is Send with the debug status code metrics kept separately for
+// category, so e.g. alerts and digests sharing a webhook can be told apart.
+func SendLabeled(category string, webhookUrl string, payload Payload) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{category: category})
+	return errs
+}
+
+func send(webhookUrl string, payload Payload, opts sendOptions) (sendResult, []error) {
+	if sendingDisabled() {
+		return sendResult{}, nil
+	}
+
+	if !allowSend() {
+		return sendResult{}, []error{ErrRateLimitedLocally}
+	}
+
+	if ValidateURL {
+		if err := ValidateWebhookURL(webhookUrl); err != nil {
+			return sendResult{}, []error{err}
+		}
+	}
+
+	if !opts.prepared {
+		if err := preparePayload(&payload); err != nil {
+			return sendResult{}, []error{err}
+		}
+	}
+
+	pooled, err := marshalPooled(payload)
 	if err != nil {
-		return []error{err}
+		return sendResult{}, []error{marshalError(payload, err)}
 	}
+	defer pooled.release()
+	payloadJson := pooled.Bytes()
+	opts.pooled = pooled
 
-	if proxy != "" {
-		proxyUrl, err := url.Parse(proxy)
-		if err != nil {
-			return []error{err}
+	if PrettyLog && os.Getenv("SLACK_GO_WEBHOOK_DEBUG") != "" {
+		if indented, err := json.MarshalIndent(payload, "", "  "); err == nil {
+			logger.Printf("Slack payload:\n%s\n", indented)
 		}
-		HttpClient.Transport = &http.Transport{Proxy: http.ProxyURL(proxyUrl)}
 	}
 
-	for {
-		req, err := http.NewRequest("POST", webhookUrl, bytes.NewBuffer(payloadJson))
+	if MaxPayloadBytes > 0 && len(payloadJson) > MaxPayloadBytes {
+		return sendResult{}, []error{fmt.Errorf("%w: %d bytes exceeds %d", ErrPayloadTooLarge, len(payloadJson), MaxPayloadBytes)}
+	}
+
+	if BeforeRetry != nil {
+		hook := BeforeRetry
+		opts.beforeRetry = func(attempt int, lastStatus int) ([]byte, error) {
+			hook(&payload, attempt, lastStatus)
+			payloadJson, err := json.Marshal(payload)
+			if err != nil {
+				return nil, marshalError(payload, err)
+			}
+			return payloadJson, nil
+		}
+	}
+
+	result, errs := post(webhookUrl, payloadJson, opts)
+	if len(errs) > 0 {
+		if !opts.noDeadLetter {
+			deadLetter(payload, errs)
+		}
+		return result, errs
+	}
+
+	// Incoming webhooks answer "ok", anything else in a successful response
+	// is a reason the message wasn't posted.
+	if body := strings.TrimSpace(string(result.body)); body != "" && body != "ok" {
+		errs = []error{fmt.Errorf("%w: %q", ErrUnexpectedResponse, body)}
+		if !opts.noDeadLetter {
+			deadLetter(payload, errs)
+		}
+		return result, errs
+	}
+
+	return result, nil
+}
+
+// preparePayload makes the changes to payload that are made before every
+// send: defaults, block text fallback, compatibility mode, colors and
+// normalizing.
+func preparePayload(payload *Payload) error {
+	applyDefaults(payload)
+
+	if BlockTextFallback {
+		applyBlockTextFallback(payload)
+	}
+
+	*payload = adaptPayload(*payload)
+
+	if payload.DefaultColor != nil {
+		applyDefaultColor(payload)
+	}
+	if AutoColor {
+		colorAttachments(payload)
+	}
+
+	if AutoNormalize {
+		payload.Attachments = append([]Attachment(nil), payload.Attachments...)
+		if err := payload.Normalize(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyDefaultColor gives attachments without a color payload.DefaultColor,
+// working on a copy of the attachments so the caller's are untouched.
+func applyDefaultColor(payload *Payload) {
+	payload.Attachments = append([]Attachment(nil), payload.Attachments...)
+	for i := range payload.Attachments {
+		if payload.Attachments[i].Color == nil {
+			payload.Attachments[i].Color = payload.DefaultColor
+		}
+	}
+}
+
+// colorAttachments colors uncolored attachments from AutoColorPalette,
+// working on a copy of the attachments so the caller's are untouched.
+func colorAttachments(payload *Payload) {
+	palette := AutoColorPalette
+	if len(palette) == 0 {
+		return
+	}
+
+	payload.Attachments = append([]Attachment(nil), payload.Attachments...)
+	next := 0
+	for i := range payload.Attachments {
+		if color := payload.Attachments[i].Color; color != nil && *color != "" {
+			continue
+		}
+		color := palette[next%len(palette)]
+		payload.Attachments[i].Color = &color
+		next++
+	}
+}
+
+func deadLetter(payload Payload, errs []error) {
+	if sink := DeadLetterSink; sink != nil {
+		sink(payload, errors.Join(errs...))
+	}
+}
+
+func applyDefaults(payload *Payload) {
+	if payload.Channel == "" {
+		payload.Channel = DefaultChannel
+	}
+	if payload.Username == "" {
+		payload.Username = DefaultUsername
+	}
+	if payload.IconEmoji == "" && payload.IconUrl == "" {
+		payload.IconEmoji = DefaultIconEmoji
+	}
+}
+
+// sendingDisabled reports whether SLACK_GO_WEBHOOK_DISABLE is set. It is a
+// hard kill switch checked before anything else, so nothing is marshaled,
+// logged or sent and every send reports success.
+func sendingDisabled() bool {
+	return os.Getenv("SLACK_GO_WEBHOOK_DISABLE") != ""
+}
+
+// pooledPayload is a marshaled payload in a buffer from payloadBufferPool.
+// The transport may still be reading a request body after the send returns,
+// so each body holds a reference and the buffer only goes back to the pool
+// once the send and every body are done with it.
+type pooledPayload struct {
+	buf  bytes.Buffer
+	enc  *json.Encoder
+	refs atomic.Int32
+}
+
+var payloadBufferPool = sync.Pool{
+	New: func() interface{} {
+		pooled := &pooledPayload{}
+		pooled.enc = json.NewEncoder(&pooled.buf)
+		return pooled
+	},
+}
+
+// marshalPooled encodes payload into a pooled buffer, with the same output
+// as json.Marshal. The caller holds a reference and must release it.
+func marshalPooled(payload Payload) (*pooledPayload, error) {
+	pooled := payloadBufferPool.Get().(*pooledPayload)
+	pooled.buf.Reset()
+	pooled.refs.Store(1)
+	if err := pooled.enc.Encode(payload); err != nil {
+		pooled.release()
+		return nil, err
+	}
+	// Encode ends the output with a newline that Marshal doesn't.
+	pooled.buf.Truncate(pooled.buf.Len() - 1)
+	return pooled, nil
+}
+
+func (pooled *pooledPayload) Bytes() []byte {
+	return pooled.buf.Bytes()
+}
+
+func (pooled *pooledPayload) release() {
+	if pooled.refs.Add(-1) == 0 {
+		payloadBufferPool.Put(pooled)
+	}
+}
+
+// body returns a request body reading the payload, which holds a reference
+// until the transport closes it.
+func (pooled *pooledPayload) body() io.ReadCloser {
+	pooled.refs.Add(1)
+	body := &pooledBody{pooled: pooled}
+	body.Reset(pooled.buf.Bytes())
+	return body
+}
+
+type pooledBody struct {
+	bytes.Reader
+	pooled *pooledPayload
+	closed atomic.Bool
+}
+
+func (body *pooledBody) Close() error {
+	if body.closed.CompareAndSwap(false, true) {
+		body.pooled.release()
+	}
+	return nil
+}
+
+// newRequest builds the POST of payloadJson, reading it straight from pooled
+// when payloadJson is its buffer.
+func newRequest(ctx context.Context, webhookUrl string, payloadJson []byte, pooled *pooledPayload) (*http.Request, error) {
+	if pooled == nil {
+		return http.NewRequestWithContext(ctx, "POST", webhookUrl, bytes.NewReader(payloadJson))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = pooled.body()
+	req.ContentLength = int64(len(payloadJson))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return pooled.body(), nil
+	}
+	return req, nil
+}
+
+func post(webhookUrl string, payloadJson []byte, opts sendOptions) (sendResult, []error) {
+	if opts.ctx == nil {
+		opts.ctx = rootContext()
+	}
+
+	var proxyUrl *url.URL
+	if opts.proxy != "" {
+		var err error
+		if proxyUrl, err = url.Parse(opts.proxy); err != nil {
+			return sendResult{}, []error{err}
+		}
+	}
+
+	lastStatus := 0
+	rateLimited := false
+	client := httpClient(proxyUrl)
+	var waited time.Duration
+
+	// retryBound returns an error if retrying after waiting wait would break
+	// the bounds set by WithMaxAttempts and WithMaxTotalWait.
+	retryBound := func(attempt int, wait time.Duration) error {
+		if opts.maxAttempts > 0 && attempt >= opts.maxAttempts {
+			return fmt.Errorf("%w: %d", ErrMaxAttempts, opts.maxAttempts)
+		}
+		if opts.maxTotalWait > 0 && waited+wait > opts.maxTotalWait {
+			return fmt.Errorf("%w: %v", ErrMaxTotalWait, opts.maxTotalWait)
+		}
+		waited += wait
+		return nil
+	}
+
+	// clampToDeadline shortens wait so a sleep doesn't run past the deadline
+	// set by SendWithDeadline.
+	clampToDeadline := func(wait time.Duration) (time.Duration, error) {
+		if opts.deadline.IsZero() {
+			return wait, nil
+		}
+		remaining := opts.deadline.Sub(clock.Now())
+		if remaining <= 0 {
+			return 0, ErrDeadlineExceeded
+		}
+		return MinDuration(wait, remaining), nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := opts.ctx.Err(); err != nil {
+			return sendResult{}, []error{err}
+		}
+
+		if attempt > 1 && opts.beforeRetry != nil {
+			var err error
+			if payloadJson, err = opts.beforeRetry(attempt, lastStatus); err != nil {
+				return sendResult{}, []error{err}
+			}
+			opts.pooled = nil
+		}
+
+		if !opts.deadline.IsZero() && !clock.Now().Before(opts.deadline) {
+			return sendResult{}, []error{ErrDeadlineExceeded}
+		}
+
+		if opts.limiter != nil {
+			if err := opts.limiter.Wait(opts.ctx); err != nil {
+				return sendResult{}, []error{err}
+			}
+		}
+
+		req, err := newRequest(opts.ctx, webhookUrl, payloadJson, opts.pooled)
+		if err != nil {
+			return sendResult{}, []error{err}
+		}
+
+		req.Header.Set("Accept", "application/json")
+		if opts.basicAuth != nil {
+			password, _ := opts.basicAuth.Password()
+			req.SetBasicAuth(opts.basicAuth.Username(), password)
+		}
+		for key, values := range opts.header {
+			req.Header[key] = values
+		}
+
+		if opts.verbose {
+			logVerboseRequest(req, attempt, payloadJson)
+		}
+
+		if err := acquireSendSlot(opts.ctx); err != nil {
+			req.Body.Close()
+			return sendResult{attempts: attempt - 1, rateLimited: rateLimited}, []error{err}
+		}
+		resp, err := client.Do(req)
+		releaseSendSlot()
 		if err != nil {
-			return []error{err}
+			if opts.verbose {
+				// The error repeats the unredacted URL, log only its cause.
+				var urlErr *url.Error
+				if errors.As(err, &urlErr) {
+					logger.Printf("Slack request failed: %v", urlErr.Err)
+				}
+			}
+			return sendResult{attempts: attempt, rateLimited: rateLimited}, []error{err}
 		}
 
-		resp, err := HttpClient.Do(req)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
-			return []error{err}
+			return sendResult{statusCode: resp.StatusCode, attempts: attempt, rateLimited: rateLimited}, []error{err}
+		}
+		if opts.verbose {
+			logVerboseResponse(resp, body)
+		}
+		lastStatus = resp.StatusCode
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rateLimited = true
 		}
+		result := sendResult{statusCode: resp.StatusCode, header: resp.Header, body: body, attempts: attempt, rateLimited: rateLimited}
 
 		if os.Getenv("SLACK_GO_WEBHOOK_DEBUG") != "" {
-			incrementStatusCode(resp.StatusCode)
+			incrementStatusCode(opts.category, resp.StatusCode)
 		}
 
-		// We alway sleep between messages, but we adapt our rate.
-		time.Sleep(StatusCodeRetryInterval)
+		if opts.shouldRetry != nil {
+			if resp.StatusCode < 400 {
+				return result, nil
+			}
 
-		if resp.StatusCode == http.StatusTooManyRequests {
-			retryAfterHeader := resp.Header.Get("Retry-After")
-			if retryAfterHeader != "" {
-				retryAfterSeconds, err := strconv.Atoi(retryAfterHeader)
+			retry, delay := opts.shouldRetry(resp.StatusCode, attempt)
+			if !retry {
+				return result, []error{newHTTPStatusError(resp)}
+			}
+			if delay, err = clampToDeadline(delay); err != nil {
+				return result, []error{err}
+			}
+			if err := retryBound(attempt, delay); err != nil {
+				return result, []error{err, newHTTPStatusError(resp)}
+			}
 
-				if err != nil {
-					return []error{fmt.Errorf("Error parsing Retry-After header: %s", retryAfterHeader)}
-				}
+			if err := clock.SleepContext(opts.ctx, delay); err != nil {
+				return result, []error{err}
+			}
+			continue
+		}
+
+		if RetryableStatuses[resp.StatusCode] {
+			retryAfter := parseRetryAfter(resp.Header)
 
-				StatusCodeRetryInterval = MinDuration(time.Duration(retryAfterSeconds)*time.Second, StatusCodeRetryInterval+StatusCodeRetryIntervalIncrement)
-			} else {
-				StatusCodeRetryInterval = MinDuration(4*time.Second, StatusCodeRetryInterval+StatusCodeRetryIntervalIncrement)
+			// We sleep before every retry, but we adapt our rate.
+			wait := CurrentRetryInterval()
+			if attempt == 1 && opts.initialBackoff > 0 {
+				wait = opts.initialBackoff
+			}
+			if wait, err = clampToDeadline(wait); err != nil {
+				return result, []error{err}
+			}
+			if err := retryBound(attempt, wait); err != nil {
+				return result, []error{err, newHTTPStatusError(resp)}
+			}
+			// Check the budget first so an exhausted budget doesn't cost a
+			// full backoff before failing.
+			if !takeRetryToken() {
+				return result, []error{ErrRetryBudgetExhausted}
+			}
+			if err := clock.SleepContext(opts.ctx, wait); err != nil {
+				return result, []error{err}
 			}
 
+			updateRetryInterval(func(interval time.Duration) time.Duration {
+				return adjustInterval(interval, resp.StatusCode, retryAfter)
+			})
 		} else if resp.StatusCode >= 400 {
-			return []error{fmt.Errorf("Error sending msg. Status: %v", resp.StatusCode)}
+			return result, []error{newHTTPStatusError(resp)}
 		} else {
-			StatusCodeRetryInterval = MaxDuration(0, StatusCodeRetryInterval-StatusCodeRetryIntervalDecrement)
+			updateRetryInterval(func(interval time.Duration) time.Duration {
+				return adjustInterval(interval, resp.StatusCode, noRetryAfter)
+			})
+			return result, nil
+		}
+	}
+}
+
+// maxRetryInterval caps the adaptive interval when Slack gives no
+// Retry-After.
+const maxRetryInterval = 4 * time.Second
+
+// noRetryAfter is passed to adjustInterval when the response had no
+// Retry-After.
+const noRetryAfter time.Duration = -1
+
+// adjustInterval returns the adaptive retry interval following a response
+// with status to a request made at interval current. Retryable statuses grow
+// it by StatusCodeRetryIntervalIncrement, capped by retryAfter or
+// maxRetryInterval without one, successes shrink it by
+// StatusCodeRetryIntervalDecrement down to 0, and other statuses leave it.
+func adjustInterval(current time.Duration, status int, retryAfter time.Duration) time.Duration {
+	switch {
+	case RetryableStatuses[status]:
+		limit := maxRetryInterval
+		if retryAfter >= 0 {
+			limit = retryAfter
+		}
+		return MaxDuration(0, MinDuration(limit, current+StatusCodeRetryIntervalIncrement))
+	case status < 400:
+		return MaxDuration(0, current-StatusCodeRetryIntervalDecrement)
+	default:
+		return current
+	}
+}
+
+// parseRetryAfter returns the wait asked for by Retry-After-Ms, which some
+// Slack compatible gateways send for finer grained waits, or else
+// Retry-After in seconds or as an HTTP date. It returns noRetryAfter if there
+// is neither or the value can't be parsed, since a proxy's odd header is no
+// reason to fail a retryable send.
+func parseRetryAfter(header http.Header) time.Duration {
+	if retryAfterMs, err := strconv.Atoi(header.Get("Retry-After-Ms")); err == nil && retryAfterMs >= 0 {
+		return time.Duration(retryAfterMs) * time.Millisecond
+	}
+
+	retryAfterHeader := header.Get("Retry-After")
+	if retryAfterSeconds, err := strconv.Atoi(retryAfterHeader); err == nil && retryAfterSeconds >= 0 {
+		return time.Duration(retryAfterSeconds) * time.Second
+	}
+	if date, err := http.ParseTime(retryAfterHeader); err == nil {
+		return MaxDuration(0, date.Sub(clock.Now()))
+	}
+
+	return noRetryAfter
+}
+
+// CurrentRetryInterval returns the adaptive interval Send is currently
+// sleeping between requests. Unlike reading StatusCodeRetryInterval directly
+// it is safe while sends are in flight.
+func CurrentRetryInterval() time.Duration {
+	retryIntervalLock.Lock()
+	defer retryIntervalLock.Unlock()
+
+	return StatusCodeRetryInterval
+}
+
+func updateRetryInterval(update func(interval time.Duration) time.Duration) {
+	retryIntervalLock.Lock()
+	defer retryIntervalLock.Unlock()
+
+	StatusCodeRetryInterval = update(StatusCodeRetryInterval)
+}
+
+// acquireSendSlot waits until fewer than MaxConcurrentSends requests are in
+// flight, or for ctx to be done. releaseSendSlot wakes waiters by closing
+// slotFreed and replacing it.
+func acquireSendSlot(ctx context.Context) error {
+	for {
+		inFlightLock.Lock()
+		if MaxConcurrentSends <= 0 || inFlightSends < MaxConcurrentSends {
+			inFlightSends++
+			inFlightLock.Unlock()
 			return nil
 		}
+		freed := slotFreed
+		inFlightLock.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 }
 
+func releaseSendSlot() {
+	inFlightLock.Lock()
+	defer inFlightLock.Unlock()
+
+	inFlightSends--
+	close(slotFreed)
+	slotFreed = make(chan struct{})
+}
+
 func StartTicker() {
 	statusCodeLock.Lock()
 	defer statusCodeLock.Unlock()
 
 	if statusCodeTicker == nil {
-		log.Printf("Initialising status code ticker (%v)\n", StatusCodeTickerInterval)
+		logger.Printf("Initialising status code ticker (%v)\n", StatusCodeTickerInterval)
 		statusCodeTicker = time.NewTicker(StatusCodeTickerInterval)
 		go func() {
 			for {
 				select {
 				case <-statusCodeTickerDone:
-					log.Printf("Exiting status code ticker (%v)",StatusCodeTickerInterval)
+					logger.Printf("Exiting status code ticker (%v)",StatusCodeTickerInterval)
 					return
-				case t := <-statusCodeTicker.C:
-					reportStatusCodes(t)
+				case <-statusCodeTicker.C:
+					reportStatusCodes(clock.Now())
 					resetStatusCodes()
 				}
 			}
@@ -200,36 +1203,50 @@ func StartTicker() {
 }
 
 func StopTicker() {
-	log.Printf("Stopping status code ticker (%v)", StatusCodeTickerInterval)
+	logger.Printf("Stopping status code ticker (%v)", StatusCodeTickerInterval)
 	statusCodeTicker.Stop()
 	statusCodeTickerDone <- true
 }
 
-func incrementStatusCode(code int) {
+func incrementStatusCode(category string, code int) {
 	statusCodeLock.Lock()
 	defer statusCodeLock.Unlock()
 
-	_, ok := statusCodeMap[code]
+	codes, ok := statusCodeMap[category]
 	if !ok {
-		statusCodeMap[code] = 1
-	} else {
-		statusCodeMap[code]++
+		codes = make(map[int]int)
+		statusCodeMap[category] = codes
 	}
+	codes[code]++
 }
 
 func reportStatusCodes(tick time.Time) {
 	statusCodeLock.Lock()
 	defer statusCodeLock.Unlock()
 
-	log.Printf("Slack HTTP response codes = %v (StatusCodeTickerInverval=%v, StatusCodeRetryInterval=%v, StatusCodeRetryIntervalIncrement=%v, StatusCodeRetryIntervalDecrement=%v)\n",
-		statusCodeMap, StatusCodeTickerInterval, StatusCodeRetryInterval, StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement)
+	categories := make([]string, 0, len(statusCodeMap))
+	for category := range statusCodeMap {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		label := "Slack HTTP response codes"
+		if category != "" {
+			label += " [" + category + "]"
+		}
+		logger.Printf("%s = %v (StatusCodeTickerInverval=%v, StatusCodeRetryInterval=%v, StatusCodeRetryIntervalIncrement=%v, StatusCodeRetryIntervalDecrement=%v)\n",
+			label, statusCodeMap[category], StatusCodeTickerInterval, CurrentRetryInterval(), StatusCodeRetryIntervalIncrement, StatusCodeRetryIntervalDecrement)
+	}
 }
 
 func resetStatusCodes() {
 	statusCodeLock.Lock()
 	defer statusCodeLock.Unlock()
 
-	for code := range statusCodeMap {
-		statusCodeMap[code] = 0
+	for _, codes := range statusCodeMap {
+		for code := range codes {
+			codes[code] = 0
+		}
 	}
 }
@@ -1,11 +1,21 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/h2non/gock"
@@ -70,3 +80,879 @@ func TestSend(t *testing.T) {
 	time.Sleep(3 * StatusCodeTickerInterval)
 
 }
+
+func TestPayloadMarkdownFalse(t *testing.T) {
+	markdown := false
+	payload := Payload{
+		Text:     "*not bold*",
+		Markdown: &markdown,
+	}
+
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(payloadJson), `"mrkdwn":false`) {
+		t.Errorf("Expected explicit mrkdwn false in %s", payloadJson)
+	}
+
+	payloadJson, err = json.Marshal(Payload{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(payloadJson), `"mrkdwn"`) {
+		t.Errorf("Expected mrkdwn to be omitted in %s", payloadJson)
+	}
+}
+
+func disableSleep(t *testing.T) {
+	SleepFunc = func(time.Duration) {}
+	t.Cleanup(func() { SleepFunc = time.Sleep })
+}
+
+func TestSendWithTrace(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/trace").
+		MatchHeader(TraceHeader, "abc123").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := SendWithTrace(context.Background(), "http://test.com/trace", Payload{Text: "traced"}, "abc123"); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	if !gock.IsDone() {
+		t.Error("Expected request carrying the trace header")
+	}
+}
+
+func TestSendPayloadTooLarge(t *testing.T) {
+	payload := Payload{
+		Text: strings.Repeat("x", MaxPayloadBytes),
+	}
+
+	errs := Send("http://test.com/large", "", payload)
+	if len(errs) != 1 || !errors.Is(errs[0], ErrPayloadTooLarge) {
+		t.Fatalf("Expected ErrPayloadTooLarge, got %v", errs)
+	}
+}
+
+func TestSendHTTPStatusError(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/404").
+		Reply(404).
+		SetHeader("X-Slack-Req-Id", "req-123")
+
+	gock.DisableNetworking()
+
+	errs := Send("http://test.com/404", "", Payload{Text: "Hello"})
+
+	var statusErr *HTTPStatusError
+	if len(errs) != 1 || !errors.As(errs[0], &statusErr) {
+		t.Fatalf("Expected HTTPStatusError, got %v", errs)
+	}
+	if statusErr.StatusCode != 404 || statusErr.RequestID != "req-123" {
+		t.Errorf("Unexpected error details: %+v", statusErr)
+	}
+}
+
+func TestDeadLetterSink(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/gone").
+		Reply(410)
+
+	gock.DisableNetworking()
+
+	var dead []Payload
+	DeadLetterSink = func(payload Payload, err error) {
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != 410 {
+			t.Errorf("Unexpected dead letter error: %v", err)
+		}
+		dead = append(dead, payload)
+	}
+	defer func() { DeadLetterSink = nil }()
+
+	if errs := Send("http://test.com/gone", "", Payload{Text: "Hello"}); len(errs) != 1 {
+		t.Fatalf("Expected one error, got %v", errs)
+	}
+	if len(dead) != 1 || dead[0].Text != "Hello" {
+		t.Errorf("Expected the payload in the dead letter sink, got %+v", dead)
+	}
+
+	defer func(limit int) { MaxPayloadBytes = limit }(MaxPayloadBytes)
+	MaxPayloadBytes = 10
+	if errs := Send("http://test.com/gone", "", Payload{Text: "Hello, world"}); len(errs) != 1 {
+		t.Fatalf("Expected one error, got %v", errs)
+	}
+	if len(dead) != 1 {
+		t.Errorf("Expected a payload refused before posting to skip the sink, got %+v", dead)
+	}
+}
+
+func TestSendWithPolicy(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/policy").
+		Times(2).
+		Reply(503)
+	gock.New("http://test.com").
+		Post("/policy").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	var attempts []int
+	policy := func(statusCode int, attempt int) (bool, time.Duration) {
+		attempts = append(attempts, attempt)
+		return statusCode == 503 && attempt < 3, time.Millisecond
+	}
+
+	if errs := SendWithPolicy("http://test.com/policy", Payload{Text: "Hello"}, policy); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(attempts) != 2 {
+		t.Errorf("Expected policy to be consulted twice, got %v", attempts)
+	}
+}
+
+func TestSendContextCancelled(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/200").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := SendContext(ctx, "http://test.com/200", "", Payload{Text: "Hello"})
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", errs)
+	}
+}
+
+type fakeLimiter struct {
+	waits int
+	block bool
+}
+
+func (limiter *fakeLimiter) Wait(ctx context.Context) error {
+	limiter.waits++
+	if limiter.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func TestSendWithLimiter(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/limited").
+		Reply(429)
+	gock.New("http://test.com").
+		Post("/limited").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	limiter := &fakeLimiter{}
+	if errs := SendWithLimiter(context.Background(), limiter, "http://test.com/limited", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if limiter.waits != 2 {
+		t.Errorf("Expected a wait before the send and the retry, got %d", limiter.waits)
+	}
+
+	gock.New("http://test.com").
+		Post("/limited").
+		Reply(200)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	limiter = &fakeLimiter{block: true}
+	errs := SendWithLimiter(ctx, limiter, "http://test.com/limited", Payload{Text: "Hello"})
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", errs)
+	}
+	if !gock.IsPending() {
+		t.Error("Expected nothing to be sent while waiting on the limiter")
+	}
+}
+
+func benchmarkPayload() Payload {
+	color := "good"
+	text := "Deploy finished"
+	attachment := Attachment{Color: &color, Text: &text}
+	attachment.AddField(Field{Title: "Version", Value: "1.2.3", Short: true})
+	attachment.AddField(Field{Title: "Commit", Value: "3e20564", Short: true})
+
+	return Payload{
+		Text:        "Hello from slack-go-webhook",
+		Username:    "robot",
+		Attachments: []Attachment{attachment},
+	}
+}
+
+func TestMarshalPooled(t *testing.T) {
+	payload := benchmarkPayload()
+
+	expected, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pooled, err := marshalPooled(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pooled.Bytes()) != string(expected) {
+		t.Errorf("Expected %s, got %s", expected, pooled.Bytes())
+	}
+
+	// A body still open when the send lets go keeps the buffer out of the
+	// pool, so marshaling another payload doesn't overwrite it.
+	body := pooled.body()
+	pooled.release()
+	other, err := marshalPooled(Payload{Text: "Something else entirely"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.release()
+	read, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if string(read) != string(expected) {
+		t.Errorf("Expected the open body to read %s, got %s", expected, read)
+	}
+}
+
+// The benchmarks build and read a request body as post does, so they compare
+// what a send costs with and without the pool.
+func benchmarkRequest(b *testing.B, payloadJson []byte, pooled *pooledPayload) {
+	req, err := newRequest(context.Background(), "http://test.com/bench", payloadJson, pooled)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := io.Copy(io.Discard, req.Body); err != nil {
+		b.Fatal(err)
+	}
+	req.Body.Close()
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	payload := benchmarkPayload()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		payloadJson, err := json.Marshal(payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkRequest(b, payloadJson, nil)
+	}
+}
+
+func BenchmarkMarshalPooled(b *testing.B) {
+	payload := benchmarkPayload()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		pooled, err := marshalPooled(payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkRequest(b, pooled.Bytes(), pooled)
+		pooled.release()
+	}
+}
+
+func TestColorAttachments(t *testing.T) {
+	red := "danger"
+	attachments := []Attachment{{}, {Color: &red}, {}}
+	payload := Payload{Attachments: attachments}
+
+	colorAttachments(&payload)
+
+	var colors []string
+	for _, attachment := range payload.Attachments {
+		colors = append(colors, *attachment.Color)
+	}
+	expected := []string{AutoColorPalette[0], "danger", AutoColorPalette[1]}
+	if !reflect.DeepEqual(colors, expected) {
+		t.Errorf("Expected colors %v, got %v", expected, colors)
+	}
+	if attachments[0].Color != nil {
+		t.Error("Expected the caller's attachments to be left alone")
+	}
+}
+
+func TestApplyDefaultColor(t *testing.T) {
+	brand, red := "#4a154b", "danger"
+	attachments := []Attachment{{}, {Color: &red}}
+	payload := Payload{Attachments: attachments, DefaultColor: &brand}
+
+	applyDefaultColor(&payload)
+
+	if *payload.Attachments[0].Color != brand || *payload.Attachments[1].Color != red {
+		t.Errorf("Expected colors %s and %s, got %s and %s", brand, red, *payload.Attachments[0].Color, *payload.Attachments[1].Color)
+	}
+	if attachments[0].Color != nil {
+		t.Error("Expected the caller's attachments to be left alone")
+	}
+
+	encoded, _ := json.Marshal(payload)
+	if strings.Contains(string(encoded), "default") {
+		t.Errorf("Expected DefaultColor not to be sent, got %s", encoded)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	channel, username, iconEmoji := DefaultChannel, DefaultUsername, DefaultIconEmoji
+	defer func() {
+		DefaultChannel, DefaultUsername, DefaultIconEmoji = channel, username, iconEmoji
+	}()
+	DefaultChannel, DefaultUsername, DefaultIconEmoji = "#alerts", "robot", ":robot_face:"
+
+	tests := []struct {
+		payload  Payload
+		expected Payload
+	}{
+		{Payload{}, Payload{Channel: "#alerts", Username: "robot", IconEmoji: ":robot_face:"}},
+		{Payload{Channel: "#deploys", Username: "deployer", IconEmoji: ":rocket:"}, Payload{Channel: "#deploys", Username: "deployer", IconEmoji: ":rocket:"}},
+		{Payload{IconUrl: "https://example.com/icon.png"}, Payload{Channel: "#alerts", Username: "robot", IconUrl: "https://example.com/icon.png"}},
+	}
+	for _, test := range tests {
+		payload := test.payload
+		applyDefaults(&payload)
+		if !reflect.DeepEqual(payload, test.expected) {
+			t.Errorf("Expected %+v for %+v, got %+v", test.expected, test.payload, payload)
+		}
+	}
+}
+
+func TestNewConfirm(t *testing.T) {
+	tests := []struct {
+		confirm  *ConfirmField
+		expected string
+	}{
+		{NewConfirm("Are you sure?", "This restarts prod"), `{"title":"Are you sure?","text":"This restarts prod"}`},
+		{NewConfirm("", "Really?").OK("Yes"), `{"text":"Really?","ok_text":"Yes"}`},
+		{NewConfirm("Deploy", "Ship it?").OK("Ship").Dismiss("Wait"), `{"title":"Deploy","text":"Ship it?","ok_text":"Ship","dismiss_text":"Wait"}`},
+	}
+	for _, test := range tests {
+		encoded, err := json.Marshal(test.confirm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, encoded)
+		}
+	}
+}
+
+func TestNewImageAttachment(t *testing.T) {
+	tests := []struct {
+		imageURL, title, text string
+		expected              string
+	}{
+		{"https://example.com/graph.png", "Latency", "Last hour", `{"fallback":"Latency","title":"Latency","text":"Last hour","image_url":"https://example.com/graph.png"}`},
+		{"https://example.com/graph.png", "", "", `{"fallback":"https://example.com/graph.png","image_url":"https://example.com/graph.png"}`},
+	}
+	for _, test := range tests {
+		encoded, err := json.Marshal(NewImageAttachment(test.imageURL, test.title, test.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, encoded)
+		}
+	}
+}
+
+func TestSetAuthor(t *testing.T) {
+	tests := []struct {
+		name, link, iconURL string
+		expected            string
+	}{
+		{"deploy-bot", "https://example.com/bot", "https://example.com/bot.png", `{"author_name":"deploy-bot","author_link":"https://example.com/bot","author_icon":"https://example.com/bot.png"}`},
+		{"deploy-bot", "", "", `{"author_name":"deploy-bot"}`},
+		{"", "", "", `{}`},
+	}
+	for _, test := range tests {
+		attachment := &Attachment{}
+		encoded, err := json.Marshal(attachment.SetAuthor(test.name, test.link, test.iconURL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, encoded)
+		}
+	}
+}
+
+func TestTruncateTextWithLink(t *testing.T) {
+	text := strings.Repeat("é", 100)
+	attachment := Attachment{Text: &text}
+
+	attachment.TruncateTextWithLink(60, "https://example.com/log")
+	suffix := "… (truncated, <https://example.com/log|full text>)"
+	if !strings.HasSuffix(*attachment.Text, suffix) || utf8.RuneCountInString(*attachment.Text) != 60 {
+		t.Errorf("Expected 60 runes ending in %q, got %q", suffix, *attachment.Text)
+	}
+	if text != strings.Repeat("é", 100) {
+		t.Error("Expected the original text to be left alone")
+	}
+
+	attachment = Attachment{Text: &text}
+	if got := *attachment.TruncateTextWithLink(20, "").Text; got != strings.Repeat("é", 7)+"… (truncated)" {
+		t.Errorf("Unexpected truncation %q", got)
+	}
+}
+
+func TestAddFieldIf(t *testing.T) {
+	attachment := &Attachment{}
+	attachment.
+		AddFieldIf(true, Field{Title: "Env", Value: "prod"}).
+		AddFieldIf(false, Field{Title: "Debug", Value: "on"}).
+		AddFieldIfNotEmpty("Commit", "abc123").
+		AddFieldIfNotEmpty("Tag", "")
+
+	var titles []string
+	for _, field := range attachment.Fields {
+		titles = append(titles, field.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"Env", "Commit"}) {
+		t.Errorf("Unexpected fields %v", titles)
+	}
+}
+
+func TestSetChannel(t *testing.T) {
+	for name, expected := range map[string]string{
+		"general":   "#general",
+		"#general":  "#general",
+		"@someone":  "@someone",
+		"C024BE91L": "C024BE91L",
+		"D024BE91L": "D024BE91L",
+		"dev-ops":   "#dev-ops",
+	} {
+		payload := Payload{}
+		payload.SetChannel(name)
+		if payload.Channel != expected {
+			t.Errorf("SetChannel(%q) = %q, expected %q", name, payload.Channel, expected)
+		}
+	}
+}
+
+func TestSendRetriesServiceUnavailable(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/503").
+		Reply(503)
+	gock.New("http://test.com").
+		Post("/503").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := Send("http://test.com/503", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected the 503 to be retried")
+	}
+}
+
+func TestSendWithResponse(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/429").
+		Times(2).
+		Reply(429)
+	gock.New("http://test.com").
+		Post("/429").
+		Reply(200)
+	gock.New("http://test.com").
+		Post("/ok").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	response, errs := SendWithResponse("http://test.com/429", Payload{Text: "Hello"})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if expected := (SendResponse{StatusCode: 200, WasRateLimited: true, RetryCount: 2}); response != expected {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+
+	response, _ = SendWithResponse("http://test.com/ok", Payload{Text: "Hello"})
+	if expected := (SendResponse{StatusCode: 200}); response != expected {
+		t.Errorf("Expected %+v, got %+v", expected, response)
+	}
+}
+
+func TestSendUnexpectedResponse(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/soft").
+		Reply(200).
+		BodyString("invalid_payload")
+
+	gock.DisableNetworking()
+
+	errs := Send("http://test.com/soft", "", Payload{Text: "Hello"})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrUnexpectedResponse) {
+		t.Fatalf("Expected ErrUnexpectedResponse, got %v", errs)
+	}
+}
+
+func TestBeforeRetry(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	BeforeRetry = func(payload *Payload, attempt int, lastStatus int) {
+		if lastStatus == 503 {
+			payload.Text = "retried"
+		}
+	}
+	defer func() { BeforeRetry = nil }()
+
+	gock.New("http://test.com").
+		Post("/retry").
+		Reply(503)
+	gock.New("http://test.com").
+		Post("/retry").
+		BodyString(`"text":"retried"`).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := Send("http://test.com/retry", "", Payload{Text: "original"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestSendDisabled(t *testing.T) {
+	defer gock.Off()
+	gock.DisableNetworking()
+
+	t.Setenv("SLACK_GO_WEBHOOK_DISABLE", "true")
+
+	if errs := Send("http://test.com/disabled", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Expected disabled send to succeed without a request, got %v", errs)
+	}
+}
+
+func TestSendWithBasicAuth(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/auth").
+		MatchHeader("Authorization", "^Basic dXNlcjpwYXNz$").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if errs := SendWithBasicAuth("http://test.com/auth", "user", "pass", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestPayloadOmitsUnsetFields(t *testing.T) {
+	attachment := Attachment{}
+	attachment.AddField(Field{Title: "Status", Value: "Completed"})
+	attachment.AddAction(Action{Type: "button", Text: "Open"})
+
+	payloadJson, err := json.Marshal(Payload{Text: "Hello", Attachments: []Attachment{attachment}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"text":"Hello","attachments":[{"fields":[{"title":"Status","value":"Completed","short":false}],"actions":[{"type":"button","text":"Open"}]}]}`
+	if string(payloadJson) != expected {
+		t.Errorf("Expected %s, got %s", expected, payloadJson)
+	}
+}
+
+func TestSendKeepsQueryParams(t *testing.T) {
+	var rawQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if errs := Send(server.URL+"/hook?key=1&z=2&a=3", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if rawQuery != "key=1&z=2&a=3" {
+		t.Errorf("Expected the query to be sent unchanged, got %q", rawQuery)
+	}
+}
+
+func TestSendWithToken(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://hooks.slack.com").
+		Post("/services/T000/B000/XXXX").
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	if url := WebhookURL("T000/B000/XXXX"); url != "https://hooks.slack.com/services/T000/B000/XXXX" {
+		t.Errorf("Unexpected webhook URL %q", url)
+	}
+	if errs := SendWithToken("T000/B000/XXXX", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestSendProxyIsPerCall(t *testing.T) {
+	var proxied, direct int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		w.Write([]byte("ok"))
+	}))
+	defer proxy.Close()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		direct++
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	if errs := Send(target.URL+"/hook", proxy.URL, Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if errs := Send(target.URL+"/hook", "", Payload{Text: "Hello"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	if proxied != 1 || direct != 1 {
+		t.Errorf("Expected one send through the proxy and one direct, got %d and %d", proxied, direct)
+	}
+	if HttpClient.Transport != nil {
+		t.Error("Expected HttpClient to be left unchanged")
+	}
+}
+
+func TestSendPayloadMarshalError(t *testing.T) {
+	var hooked *PayloadMarshalError
+	OnMarshalError = func(payload Payload, err *PayloadMarshalError) {
+		hooked = err
+	}
+	defer func() { OnMarshalError = nil }()
+
+	payload := Payload{
+		Text: "Hello",
+		Metadata: &Metadata{
+			EventType:    "deploy",
+			EventPayload: map[string]interface{}{"version": "1.2.3", "callback": func() {}},
+		},
+	}
+	errs := Send("http://test.com/never", "", payload)
+
+	var marshalErr *PayloadMarshalError
+	if len(errs) != 1 || !errors.As(errs[0], &marshalErr) {
+		t.Fatalf("Expected a PayloadMarshalError, got %v", errs)
+	}
+	if marshalErr.Field != "metadata.event_payload.callback" {
+		t.Errorf("Expected the failing field to be found, got %q", marshalErr.Field)
+	}
+	if hooked != marshalErr {
+		t.Error("Expected OnMarshalError to be called with the error")
+	}
+}
+
+func TestSendWithFallbackChannels(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("http://test.com").
+		Post("/hook").
+		Reply(404).
+		BodyString("channel_not_found")
+	gock.New("http://test.com").
+		Post("/hook").
+		Reply(410).
+		BodyString("channel_is_archived")
+	gock.New("http://test.com").
+		Post("/hook").
+		Reply(200).
+		BodyString("ok")
+	gock.New("http://test.com").
+		Post("/invalid").
+		Reply(400).
+		BodyString("invalid_payload")
+
+	gock.DisableNetworking()
+
+	DeadLetterSink = func(payload Payload, err error) {
+		t.Errorf("Unexpected dead letter for %q: %v", payload.Channel, err)
+	}
+	defer func() { DeadLetterSink = nil }()
+
+	errs := SendWithFallbackChannels("http://test.com/hook", Payload{Channel: "#gone", Text: "Hello"}, []string{"#archived", "#ops"})
+	if len(errs) > 0 {
+		t.Errorf("Expected a fallback channel to succeed, got %v", errs)
+	}
+
+	var dead []Payload
+	DeadLetterSink = func(payload Payload, err error) { dead = append(dead, payload) }
+	errs = SendWithFallbackChannels("http://test.com/invalid", Payload{Channel: "#alerts", Text: "Hello"}, []string{"#ops"})
+	if len(errs) != 1 || len(dead) != 1 || dead[0].Channel != "#alerts" {
+		t.Errorf("Expected other errors to fail without trying the fallback, got %v and %+v", errs, dead)
+	}
+}
+
+func TestSendAttachments(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://test.com").
+		Post("/attachments").
+		BodyString(`{"attachments":[{"color":"good","text":"Deployed"}]}`).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	color, text := "good", "Deployed"
+	if errs := SendAttachments("http://test.com/attachments", Attachment{Color: &color, Text: &text}); len(errs) > 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestMaxConcurrentSends(t *testing.T) {
+	defer func(limit int) { MaxConcurrentSends = limit }(MaxConcurrentSends)
+	MaxConcurrentSends = 2
+
+	var lock sync.Mutex
+	inFlight, most := 0, 0
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		lock.Unlock()
+
+		<-release
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs := Send(server.URL, "", Payload{Text: "Hello"}); len(errs) > 0 {
+				t.Errorf("Unexpected errors: %v", errs)
+			}
+		}()
+	}
+
+	for {
+		lock.Lock()
+		full := inFlight == 2
+		lock.Unlock()
+		if full {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errs := SendWithTrace(ctx, server.URL, Payload{Text: "Hello"}, "trace")
+	if len(errs) != 1 || errs[0] != context.DeadlineExceeded {
+		t.Errorf("Expected a send waiting for a slot to give up with its context, got %v", errs)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if most != 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", most)
+	}
+}
+
+func TestSendLabeled(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+	t.Setenv("SLACK_GO_WEBHOOK_DEBUG", "true")
+
+	gock.New("http://test.com").
+		Post("/labeled").
+		Times(2).
+		Reply(200)
+	gock.New("http://test.com").
+		Post("/labeled").
+		Reply(404)
+
+	gock.DisableNetworking()
+
+	defer func() {
+		statusCodeLock.Lock()
+		delete(statusCodeMap, "deploys")
+		statusCodeLock.Unlock()
+	}()
+
+	SendLabeled("deploys", "http://test.com/labeled", Payload{Text: "Hello"})
+	SendLabeled("deploys", "http://test.com/labeled", Payload{Text: "Hello"})
+	SendLabeled("deploys", "http://test.com/labeled", Payload{Text: "Hello"})
+
+	statusCodeLock.Lock()
+	codes := statusCodeMap["deploys"]
+	statusCodeLock.Unlock()
+	if !reflect.DeepEqual(codes, map[int]int{200: 2, 404: 1}) {
+		t.Errorf("Expected the deploys category to count 2x200 and 1x404, got %v", codes)
+	}
+
+	log := &bufferLogger{}
+	SetLogger(log)
+	defer SetLogger(nil)
+
+	reportStatusCodes(time.Now())
+	var reported bool
+	for _, line := range log.lines {
+		reported = reported || strings.HasPrefix(line, "Slack HTTP response codes [deploys] = map[200:2 404:1]")
+	}
+	if !reported {
+		t.Errorf("Expected the deploys counts to be reported, got %q", log.lines)
+	}
+}
@@ -0,0 +1,364 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SendSplit sends payload like Send, but when it is larger than
+// MaxPayloadBytes it is spread over several messages instead of failing.
+// Attachments are packed into as few messages as fit and long text is split
+// on newlines, truncating any single line that is too long by itself.
+// Sending stops at the first message that fails.
+func SendSplit(webhookUrl string, payload Payload) []error {
+	if sendingDisabled() {
+		return nil
+	}
+
+	// The parts are sized as they will be sent, so the payload is prepared
+	// before splitting rather than each part as it is sent.
+	if err := preparePayload(&payload); err != nil {
+		return []error{err}
+	}
+
+	payloads, err := splitPayload(payload)
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, part := range payloads {
+		if _, errs := send(webhookUrl, part, sendOptions{prepared: true}); len(errs) > 0 {
+			return errs
+		}
+	}
+
+	return nil
+}
+
+func splitPayload(payload Payload) ([]Payload, error) {
+	if fits, err := payloadFits(payload); err != nil || fits {
+		return []Payload{payload}, err
+	}
+
+	head := payload
+	head.Attachments = nil
+
+	parts := []Payload{head}
+	if fits, err := payloadFits(head); err != nil {
+		return nil, err
+	} else if !fits {
+		if parts, err = splitText(head); err != nil {
+			return nil, err
+		}
+	}
+
+	// Continuation messages keep the sender and destination but not the
+	// message body.
+	continuation := Payload{
+		Parse:     payload.Parse,
+		Username:  payload.Username,
+		IconUrl:   payload.IconUrl,
+		IconEmoji: payload.IconEmoji,
+		Channel:   payload.Channel,
+		// Attachments moved to continuations keep the payload's default.
+		DefaultColor: payload.DefaultColor,
+	}
+
+	for _, attachment := range payload.Attachments {
+		last := &parts[len(parts)-1]
+		candidate := *last
+		candidate.Attachments = append(append([]Attachment{}, last.Attachments...), attachment)
+
+		fits, err := payloadFits(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			*last = candidate
+			continue
+		}
+
+		next := continuation
+		next.Attachments = []Attachment{attachment}
+		if fits, err := payloadFits(next); err != nil {
+			return nil, err
+		} else if !fits {
+			return nil, fmt.Errorf("%w: a single attachment exceeds %d bytes", ErrPayloadTooLarge, MaxPayloadBytes)
+		}
+		parts = append(parts, next)
+	}
+
+	return parts, nil
+}
+
+// splitText splits payload.Text on newlines into as few payloads as fit.
+// Blocks and metadata go with the first part only, so they aren't repeated.
+// Parts are sized from the marshaled size of the rest of the payload plus
+// the escaped size of each line, rather than by marshaling every candidate.
+func splitText(payload Payload) ([]Payload, error) {
+	first := payload
+	rest := payload
+	rest.Blocks, rest.Metadata = nil, nil
+
+	firstRoom, err := textRoom(first)
+	if err != nil {
+		return nil, err
+	}
+	restRoom, err := textRoom(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		parts   []Payload
+		current strings.Builder
+		size    int
+		room    = firstRoom
+	)
+
+	flush := func() {
+		part := rest
+		if len(parts) == 0 {
+			part = first
+		}
+		part.Text = current.String()
+		parts = append(parts, part)
+		current.Reset()
+		size, room = 0, restRoom
+	}
+
+	for _, line := range strings.SplitAfter(payload.Text, "\n") {
+		if line == "" {
+			continue
+		}
+		lineSize := escapedLen(line)
+		if current.Len() > 0 && size+lineSize > room {
+			flush()
+		}
+		if lineSize > room {
+			if line, lineSize = truncateToRoom(line, room); line == "" {
+				return nil, fmt.Errorf("%w: payload doesn't fit in %d bytes even without text", ErrPayloadTooLarge, MaxPayloadBytes)
+			}
+		}
+		current.WriteString(line)
+		size += lineSize
+	}
+
+	if current.Len() > 0 {
+		flush()
+	}
+
+	return parts, nil
+}
+
+// textRoom returns how many bytes of escaped text payload has room for
+// within MaxPayloadBytes.
+func textRoom(payload Payload) (int, error) {
+	payload.Text = "x"
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return MaxPayloadBytes - (len(payloadJson) - len(payload.Text)), nil
+}
+
+// escapedLen returns the length of s once marshaled as a JSON string, without
+// the quotes. Escaping is per character, so the escaped length of a string
+// is the sum of the escaped lengths of its pieces.
+func escapedLen(s string) int {
+	escaped, _ := json.Marshal(s)
+	return len(escaped) - 2
+}
+
+// truncateToRoom returns the longest prefix of text that, ended with an
+// ellipsis, has an escaped length that fits in room, and that length.
+func truncateToRoom(text string, room int) (string, int) {
+	ellipsis := escapedLen("…")
+	end, size := 0, 0
+	for end < len(text) {
+		_, width := utf8.DecodeRuneInString(text[end:])
+		runeSize := escapedLen(text[end : end+width])
+		if size+runeSize+ellipsis > room {
+			break
+		}
+		end += width
+		size += runeSize
+	}
+
+	if end == 0 {
+		return "", 0
+	}
+	return text[:end] + "…", size + ellipsis
+}
+
+func payloadFits(payload Payload) (bool, error) {
+	if MaxPayloadBytes <= 0 {
+		return true, nil
+	}
+
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	return len(payloadJson) <= MaxPayloadBytes, nil
+}
+
+// SendLongText sends text as a series of messages of at most maxChunk
+// characters, split on line boundaries, one after the other like Send. A
+// code block split between messages is closed at the end of one and
+// reopened at the start of the next. Lines longer than maxChunk are broken
+// up. Blank lines and code blocks that would be left empty at a split are
+// dropped. Sending stops at the first message that fails.
+func SendLongText(webhookUrl string, text string, maxChunk int) []error {
+	for _, chunk := range splitLongText(text, maxChunk) {
+		if errs := Send(webhookUrl, "", Payload{Text: chunk}); len(errs) > 0 {
+			return errs
+		}
+	}
+
+	return nil
+}
+
+const codeFence = "```"
+
+func splitLongText(text string, maxChunk int) []string {
+	if maxChunk <= 0 || utf8.RuneCountInString(text) <= maxChunk {
+		return []string{text}
+	}
+
+	var (
+		chunks  []string
+		current []rune
+		// inFence is whether current ends inside a code block.
+		inFence bool
+	)
+
+	closing := func(runes []rune, open bool) int {
+		if !open {
+			return 0
+		}
+		if len(runes) > 0 && runes[len(runes)-1] != '\n' {
+			return len(codeFence) + 1
+		}
+		return len(codeFence)
+	}
+	// hasContent is whether current holds more than blank lines and fences
+	// opening code blocks, so it is worth a message of its own.
+	hasContent := func() bool {
+		for _, line := range strings.Split(string(current), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			opening := strings.HasPrefix(line, codeFence) && strings.Count(line, codeFence) == 1 && !strings.ContainsAny(line, " \t")
+			if !opening {
+				return true
+			}
+		}
+		return false
+	}
+	flush := func() {
+		if !hasContent() {
+			// Drop what would be an empty message or code block, but keep
+			// the fence of a code block that has been opened.
+			opening := ""
+			if inFence {
+				lines := strings.Split(strings.TrimRight(string(current), "\n"), "\n")
+				opening = strings.TrimSpace(lines[len(lines)-1]) + "\n"
+			}
+			current = []rune(opening)
+			return
+		}
+		chunk := string(current)
+		if inFence {
+			if !strings.HasSuffix(chunk, "\n") {
+				chunk += "\n"
+			}
+			chunk += codeFence
+		}
+		chunks = append(chunks, chunk)
+
+		current = nil
+		if inFence {
+			current = []rune(codeFence + "\n")
+		}
+	}
+	// fit returns how many of runes fit after current, never cutting
+	// through a fence, and whether a code block is open after them.
+	fit := func(runes []rune) (int, bool) {
+		n := maxChunk - len(current)
+		if n > len(runes) {
+			n = len(runes)
+		}
+		for ; n > 0; n-- {
+			if n < len(runes) && splitsFence(runes, n) {
+				continue
+			}
+			open := inFence != (strings.Count(string(runes[:n]), codeFence)%2 == 1)
+			if len(current)+n+closing(runes[:n], open) <= maxChunk {
+				return n, open
+			}
+		}
+		return 0, inFence
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		runes := []rune(line)
+		open := inFence != (strings.Count(line, codeFence)%2 == 1)
+
+		candidate := append(current[:len(current):len(current)], runes...)
+		if len(candidate)+closing(candidate, open) <= maxChunk {
+			current, inFence = candidate, open
+			continue
+		}
+
+		flush()
+		candidate = append(current[:len(current):len(current)], runes...)
+		if len(candidate)+closing(candidate, open) <= maxChunk {
+			current, inFence = candidate, open
+			continue
+		}
+
+		// The line doesn't fit in a message of its own, break it up,
+		// following the code blocks each piece opens or closes.
+		for len(runes) > 0 {
+			n, pieceOpen := fit(runes)
+			if n == 0 {
+				if hasContent() {
+					flush()
+					continue
+				}
+				n, pieceOpen = 1, inFence
+			}
+			current = append(current, runes[:n]...)
+			runes = runes[n:]
+			inFence = pieceOpen
+			if len(runes) > 0 {
+				flush()
+			}
+		}
+	}
+
+	if hasContent() {
+		chunks = append(chunks, string(current))
+	}
+
+	return chunks
+}
+
+// splitsFence reports whether cutting runes at n would break up a fence.
+func splitsFence(runes []rune, n int) bool {
+	for start := n - len(codeFence) + 1; start < n; start++ {
+		if start >= 0 && start+len(codeFence) <= len(runes) && string(runes[start:start+len(codeFence)]) == codeFence {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package slack
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitPayload(t *testing.T) {
+	defer func(max int) { MaxPayloadBytes = max }(MaxPayloadBytes)
+	MaxPayloadBytes = 1000
+
+	text := strings.Repeat("x", 300)
+	payload := Payload{Text: "Digest", Username: "robot"}
+	for i := 0; i < 6; i++ {
+		payload.Attachments = append(payload.Attachments, Attachment{Text: &text})
+	}
+
+	parts, err := splitPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("Expected payload to be split, got %d parts", len(parts))
+	}
+
+	attachments := 0
+	for _, part := range parts {
+		if fits, _ := payloadFits(part); !fits {
+			t.Errorf("Part exceeds MaxPayloadBytes: %+v", part)
+		}
+		if part.Username != "robot" {
+			t.Errorf("Expected username on every part, got %q", part.Username)
+		}
+		attachments += len(part.Attachments)
+	}
+	if attachments != len(payload.Attachments) {
+		t.Errorf("Expected %d attachments across parts, got %d", len(payload.Attachments), attachments)
+	}
+
+	lines := strings.Repeat(strings.Repeat("y", 90)+"\n", 30)
+	parts, err = splitPayload(Payload{Text: lines})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var joined strings.Builder
+	for _, part := range parts {
+		if fits, _ := payloadFits(part); !fits {
+			t.Errorf("Part exceeds MaxPayloadBytes: %d bytes of text", len(part.Text))
+		}
+		joined.WriteString(part.Text)
+	}
+	if joined.String() != lines {
+		t.Error("Expected text parts to join back into the original text")
+	}
+}
+
+func TestSendSplitSizesPreparedPayload(t *testing.T) {
+	defer func(max int) { MaxPayloadBytes = max }(MaxPayloadBytes)
+	MaxPayloadBytes = 40000
+	disableSleep(t)
+
+	var sizes []int
+	var uncolored int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		sizes = append(sizes, len(body))
+		for _, attachment := range payload.Attachments {
+			if attachment.Color == nil {
+				uncolored++
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The default color is only added to each attachment as it is sent, so
+	// parts sized without it would come out too large.
+	color := "#36a64f"
+	payload := Payload{Text: "Digest", DefaultColor: &color}
+	text := strings.Repeat("x", 150)
+	for i := 0; i < 400; i++ {
+		payload.Attachments = append(payload.Attachments, Attachment{Text: &text})
+	}
+
+	if errs := SendSplit(server.URL, payload); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(sizes) < 2 {
+		t.Errorf("Expected the payload to be split, got %d messages", len(sizes))
+	}
+	for i, size := range sizes {
+		if size > MaxPayloadBytes {
+			t.Errorf("Expected message %d to fit in %d bytes, got %d", i, MaxPayloadBytes, size)
+		}
+	}
+	if uncolored > 0 {
+		t.Errorf("Expected every attachment to get the default color, %d didn't", uncolored)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	defer func(limit int) { MaxPayloadBytes = limit }(MaxPayloadBytes)
+	MaxPayloadBytes = 400
+
+	// <, > and & grow when marshaled, so the parts must be sized on the
+	// escaped text.
+	lines := strings.Repeat("a < b && c > d\n", 40)
+	long := strings.Repeat("z&", 300)
+	payload := Payload{
+		Text:     lines + long,
+		Username: "robot",
+		Blocks:   []Block{NewHeaderBlock("Digest")},
+		Metadata: &Metadata{EventType: "digest"},
+	}
+
+	parts, err := splitText(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) < 3 {
+		t.Fatalf("Expected the text to be split, got %d parts", len(parts))
+	}
+
+	var joined strings.Builder
+	for i, part := range parts {
+		if fits, _ := payloadFits(part); !fits {
+			t.Errorf("Part %d exceeds MaxPayloadBytes", i)
+		}
+		if part.Username != "robot" {
+			t.Errorf("Expected username on every part, got %q", part.Username)
+		}
+		if first := i == 0; first != (len(part.Blocks) > 0) || first != (part.Metadata != nil) {
+			t.Errorf("Expected blocks and metadata on the first part only, part %d has %d blocks and metadata %v", i, len(part.Blocks), part.Metadata)
+		}
+		joined.WriteString(part.Text)
+	}
+
+	text := joined.String()
+	if !strings.HasPrefix(text, lines) {
+		t.Error("Expected the lines to join back into the original text")
+	}
+	if last := parts[len(parts)-1].Text; !strings.HasPrefix(long, strings.TrimSuffix(last, "…")) || !strings.HasSuffix(last, "…") {
+		t.Errorf("Expected the overlong line to be truncated, got %q", last)
+	}
+
+	// A part one more line long wouldn't have fit.
+	for i := 0; i < len(parts)-2; i++ {
+		next, _, _ := strings.Cut(parts[i+1].Text, "\n")
+		candidate := parts[i]
+		candidate.Text += next + "\n"
+		if fits, _ := payloadFits(candidate); fits {
+			t.Errorf("Expected part %d to be as full as fits", i)
+		}
+	}
+}
+
+func TestSplitLongText(t *testing.T) {
+	text := "line one\nline two\n```\ncode one\ncode two\n```\nafter\n"
+	x := strings.Repeat("x", 50)
+
+	tests := []struct {
+		name     string
+		text     string
+		maxChunk int
+		expected []string
+	}{
+		{"code block", text, 20, []string{
+			"line one\nline two\n",
+			"```\ncode one\n```",
+			"```\ncode two\n```\n",
+			"after\n",
+		}},
+		{"no limit", text, 0, []string{text}},
+		{"long line", strings.Repeat("x", 25), 10, []string{"xxxxxxxxxx", "xxxxxxxxxx", "xxxxx"}},
+		{"long line closing a code block", "```go\n" + x + "```\nafter\n", 27, []string{
+			"```go\n" + x[:17] + "\n```",
+			"```\n" + x[17:36] + "\n```",
+			"```\n" + x[36:] + "```\n",
+			"after\n",
+		}},
+		{"long line right after the fence", "```\n" + x + "```\n", 27, []string{
+			"```\n" + x[:19] + "\n```",
+			"```\n" + x[19:38] + "\n```",
+			"```\n" + x[38:] + "```\n",
+		}},
+		{"long line opening a code block", x[:30] + "```" + x[:10] + "\n```\n", 20, []string{
+			x[:20],
+			x[:10] + "```" + x[:3] + "\n```",
+			"```\n" + x[:7] + "\n```\n",
+		}},
+		{"blank lines", "one\n" + strings.Repeat("\n", 25) + "two\n", 10, []string{"one\n\n\n\n\n\n\n", "two\n"}},
+	}
+	for _, test := range tests {
+		chunks := splitLongText(test.text, test.maxChunk)
+		if !reflect.DeepEqual(chunks, test.expected) {
+			t.Errorf("%s: expected chunks %q, got %q", test.name, test.expected, chunks)
+		}
+		for _, chunk := range chunks {
+			if test.maxChunk > 0 && utf8.RuneCountInString(chunk) > test.maxChunk {
+				t.Errorf("%s: chunk %q is longer than %d", test.name, chunk, test.maxChunk)
+			}
+			if strings.TrimSpace(chunk) == "" {
+				t.Errorf("%s: unexpected blank chunk %q", test.name, chunk)
+			}
+		}
+	}
+}
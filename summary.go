@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"strings"
+)
+
+// Summary returns a plain text approximation of how payload will render,
+// useful for previews and test output without posting to Slack.
+func (payload Payload) Summary() string {
+	var lines []string
+	add := func(s string) {
+		if s != "" {
+			lines = append(lines, s)
+		}
+	}
+
+	add(payload.Text)
+	for _, block := range payload.Blocks {
+		add(blockSummary(block))
+	}
+
+	for _, attachment := range payload.Attachments {
+		lines = append(lines, "---")
+		add(stringValue(attachment.PreText))
+		add(stringValue(attachment.AuthorName))
+		add(stringValue(attachment.Title))
+		add(stringValue(attachment.Text))
+		for _, field := range attachment.Fields {
+			add(field.Title + ": " + field.Value)
+		}
+		for _, block := range attachment.Blocks {
+			add(blockSummary(block))
+		}
+		var actions []string
+		for _, action := range attachment.Actions {
+			actions = append(actions, "["+action.Text+"]")
+		}
+		add(strings.Join(actions, " "))
+		add(stringValue(attachment.Footer))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// BlockTextFallback makes Send fill in an empty Text with a plain text
+// rendering of the payload's blocks, so the message stays readable where
+// blocks aren't supported, e.g. on gateways that ignore them.
+var BlockTextFallback = false
+
+func applyBlockTextFallback(payload *Payload) {
+	if payload.Text != "" {
+		return
+	}
+
+	var lines []string
+	for _, block := range payload.Blocks {
+		if text := blockSummary(block); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	payload.Text = strings.Join(lines, "\n")
+}
+
+func blockSummary(block Block) string {
+	switch b := block.(type) {
+	case HeaderBlock:
+		if b.Text != nil {
+			return b.Text.Text
+		}
+	case RichTextBlock:
+		var parts []string
+		for _, element := range b.Elements {
+			var runs []RichText
+			switch e := element.(type) {
+			case RichTextSection:
+				runs = e.Elements
+			case RichTextPreformatted:
+				runs = e.Elements
+			}
+			var text strings.Builder
+			for _, run := range runs {
+				text.WriteString(run.Text)
+			}
+			parts = append(parts, text.String())
+		}
+		return strings.Join(parts, "\n")
+	case ActionsBlock:
+		var buttons []string
+		for _, button := range b.Elements {
+			if button.Text != nil {
+				buttons = append(buttons, "["+button.Text.Text+"]")
+			}
+		}
+		return strings.Join(buttons, " ")
+	}
+	return ""
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,39 @@
+package slack
+
+import "testing"
+
+func TestSummary(t *testing.T) {
+	title := "Build #42"
+	attachment := Attachment{Title: &title}
+	attachment.AddField(Field{Title: "Branch", Value: "main"})
+	attachment.AddAction(Action{Type: "button", Text: "Open"})
+
+	payload := Payload{
+		Text:        "Build finished",
+		Blocks:      []Block{NewHeaderBlock("CI")},
+		Attachments: []Attachment{attachment},
+	}
+
+	expected := "Build finished\nCI\n---\nBuild #42\nBranch: main\n[Open]"
+	if summary := payload.Summary(); summary != expected {
+		t.Errorf("Expected %q, got %q", expected, summary)
+	}
+}
+
+func TestApplyBlockTextFallback(t *testing.T) {
+	payload := Payload{Blocks: []Block{
+		NewHeaderBlock("Deploy"),
+		NewRichTextBlock(NewRichTextSection(RichTextText("api is live"))),
+	}}
+
+	applyBlockTextFallback(&payload)
+	if payload.Text != "Deploy\napi is live" {
+		t.Errorf("Unexpected fallback text %q", payload.Text)
+	}
+
+	payload = Payload{Text: "Own text", Blocks: []Block{NewHeaderBlock("Deploy")}}
+	applyBlockTextFallback(&payload)
+	if payload.Text != "Own text" {
+		t.Errorf("Expected existing text to be kept, got %q", payload.Text)
+	}
+}
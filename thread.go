@@ -0,0 +1,29 @@
+package slack
+
+// Thread keeps track of a message posted with the Web API so that follow ups
+// can be posted as replies to it.
+type Thread struct {
+	token   string
+	Channel string
+	Ts      string
+}
+
+// StartThread posts payload via chat.postMessage and returns a Thread for
+// replying to it.
+func StartThread(token string, payload Payload) (*Thread, []error) {
+	var response APIResponse
+	if errs := apiCall(token, "chat.postMessage", payload, &response); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &Thread{token: token, Channel: response.Channel, Ts: response.Ts}, nil
+}
+
+// Reply posts payload in the thread.
+func (thread *Thread) Reply(payload Payload) []error {
+	payload.Channel = thread.Channel
+	payload.ThreadTs = thread.Ts
+
+	var response APIResponse
+	return apiCall(thread.token, "chat.postMessage", payload, &response)
+}
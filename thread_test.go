@@ -0,0 +1,45 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+func TestThread(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	defer func(apiURL string) { APIURL = apiURL }(APIURL)
+	APIURL = "http://test.com/api/"
+
+	gock.New("http://test.com").
+		Post("/api/chat.postMessage").
+		MatchHeader("Authorization", "Bearer xoxb-test").
+		BodyString(`{"channel":"#deploys","text":"Deploying"}`).
+		Reply(200).
+		JSON(`{"ok":true,"channel":"C123","ts":"1503435956.000247"}`)
+	gock.New("http://test.com").
+		Post("/api/chat.postMessage").
+		MatchHeader("Authorization", "Bearer xoxb-test").
+		BodyString(`{"channel":"C123","thread_ts":"1503435956.000247","text":"Done"}`).
+		Reply(200).
+		JSON(`{"ok":true,"channel":"C123","ts":"1503435957.000100"}`)
+
+	gock.DisableNetworking()
+
+	thread, errs := StartThread("xoxb-test", Payload{Channel: "#deploys", Text: "Deploying"})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if thread.Channel != "C123" || thread.Ts != "1503435956.000247" {
+		t.Errorf("Expected the thread to be C123 1503435956.000247, got %s %s", thread.Channel, thread.Ts)
+	}
+
+	if errs := thread.Reply(Payload{Channel: "#elsewhere", Text: "Done"}); len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if !gock.IsDone() {
+		t.Error("Expected the reply to be posted in the thread")
+	}
+}
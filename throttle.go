@@ -0,0 +1,31 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// ThrottledSender spaces its sends at least minInterval apart, which is often
+// all a simple script looping over messages needs.
+type ThrottledSender struct {
+	minInterval time.Duration
+	lock        sync.Mutex
+	last        time.Time
+}
+
+func NewThrottledSender(minInterval time.Duration) *ThrottledSender {
+	return &ThrottledSender{minInterval: minInterval}
+}
+
+func (sender *ThrottledSender) Send(webhookUrl string, payload Payload) []error {
+	sender.lock.Lock()
+	if !sender.last.IsZero() {
+		if wait := sender.minInterval - clock.Now().Sub(sender.last); wait > 0 {
+			clock.Sleep(wait)
+		}
+	}
+	sender.last = clock.Now()
+	sender.lock.Unlock()
+
+	return Send(webhookUrl, "", payload)
+}
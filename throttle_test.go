@@ -0,0 +1,39 @@
+package slack
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+)
+
+func TestThrottledSender(t *testing.T) {
+	defer gock.Off()
+
+	fake := &fakeClock{now: time.Unix(0, 0)}
+	SetClock(fake)
+	defer SetClock(nil)
+
+	gock.New("http://test.com").
+		Post("/throttled").
+		Times(3).
+		Reply(200)
+
+	gock.DisableNetworking()
+
+	sender := NewThrottledSender(time.Second)
+	for i := 0; i < 3; i++ {
+		if errs := sender.Send("http://test.com/throttled", Payload{Text: "Hello"}); len(errs) > 0 {
+			t.Fatalf("Unexpected errors: %v", errs)
+		}
+		if i == 1 {
+			fake.now = fake.now.Add(400 * time.Millisecond)
+		}
+	}
+
+	expected := []time.Duration{time.Second, 600 * time.Millisecond}
+	if !reflect.DeepEqual(fake.sleeps, expected) {
+		t.Errorf("Expected sleeps %v, got %v", expected, fake.sleeps)
+	}
+}
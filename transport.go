@@ -0,0 +1,89 @@
+package slack
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	// DialTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout configure
+	// the transports this package builds, giving finer control than
+	// HttpClient.Timeout over a flaky path to Slack. While all are zero the
+	// default transport is used unchanged. They are ignored when
+	// HttpClient.Transport is set, as that transport is used as it is.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	transportLock sync.Mutex
+	transports    = make(map[transportKey]*http.Transport)
+)
+
+type transportKey struct {
+	proxy                 string
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+}
+
+func timeoutsSet() bool {
+	return DialTimeout != 0 || TLSHandshakeTimeout != 0 || ResponseHeaderTimeout != 0
+}
+
+// transportFor returns a transport using proxyUrl, or the proxy from
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY like http.DefaultTransport when nil,
+// and the configured timeouts. Transports are shared between sends with the
+// same settings so connections are reused.
+func transportFor(proxyUrl *url.URL) *http.Transport {
+	key := transportKey{
+		dialTimeout:           DialTimeout,
+		tlsHandshakeTimeout:   TLSHandshakeTimeout,
+		responseHeaderTimeout: ResponseHeaderTimeout,
+	}
+	if proxyUrl != nil {
+		key.proxy = proxyUrl.String()
+	}
+
+	transportLock.Lock()
+	defer transportLock.Unlock()
+
+	if transport, ok := transports[key]; ok {
+		return transport
+	}
+
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   TLSHandshakeTimeout,
+		ResponseHeaderTimeout: ResponseHeaderTimeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxyUrl != nil {
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	transports[key] = transport
+
+	return transport
+}
+
+// httpClient returns the client for a send through proxyUrl, or directly when
+// nil. That is HttpClient itself unless a proxy or timeouts need a transport
+// of the package's own, when it is a copy of HttpClient using one, so
+// HttpClient is never changed and a proxy only applies to its own send.
+func httpClient(proxyUrl *url.URL) *http.Client {
+	if proxyUrl == nil && (!timeoutsSet() || HttpClient.Transport != nil) {
+		return HttpClient
+	}
+
+	client := *HttpClient
+	client.Transport = transportFor(proxyUrl)
+	return &client
+}
@@ -0,0 +1,66 @@
+package slack
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestProxyFromEnvironment sends from a child process, as the environment
+// proxy settings are only read once per process.
+func TestProxyFromEnvironment(t *testing.T) {
+	if os.Getenv("SLACK_GO_WEBHOOK_PROXY_CHILD") != "" {
+		if errs := Send("http://slack.test/hook", "", Payload{Text: "Hello"}); len(errs) > 0 {
+			t.Fatalf("Unexpected errors with the default transport: %v", errs)
+		}
+
+		defer func(timeout time.Duration) { DialTimeout = timeout }(DialTimeout)
+		DialTimeout = 5 * time.Second
+		if errs := Send("http://slack.test/hook", "", Payload{Text: "Hello"}); len(errs) > 0 {
+			t.Fatalf("Unexpected errors with the package transport: %v", errs)
+		}
+		return
+	}
+
+	var proxied int
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "slack.test" {
+			proxied++
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer proxy.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProxyFromEnvironment$")
+	cmd.Env = append(os.Environ(), "SLACK_GO_WEBHOOK_PROXY_CHILD=1", "HTTP_PROXY="+proxy.URL, "NO_PROXY=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Child failed: %v\n%s", err, output)
+	}
+
+	if proxied != 2 {
+		t.Errorf("Expected both sends to go through HTTP_PROXY, got %d", proxied)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	defer func(timeout time.Duration) { ResponseHeaderTimeout = timeout }(ResponseHeaderTimeout)
+	ResponseHeaderTimeout = 50 * time.Millisecond
+
+	errs := Send(server.URL, "", Payload{Text: "Hello"})
+	var netErr net.Error
+	if len(errs) != 1 || !errors.As(errs[0], &netErr) || !netErr.Timeout() {
+		t.Fatalf("Expected a timeout error, got %v", errs)
+	}
+}
@@ -0,0 +1,189 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MaxFieldsPerAttachment is the most fields Slack will lay out sensibly in a
+// single attachment.
+const MaxFieldsPerAttachment = 10
+
+// MaxActionsPerAttachment is the most buttons Slack accepts on an attachment.
+const MaxActionsPerAttachment = 5
+
+const (
+	StyleDefault = "default"
+	StylePrimary = "primary"
+	StyleDanger  = "danger"
+)
+
+// Parse modes Slack accepts for Payload.Parse.
+const (
+	ParseFull = "full"
+	ParseNone = "none"
+)
+
+// SetParse sets the parse mode, rejecting anything but ParseFull and
+// ParseNone, which Slack would silently ignore.
+func (payload *Payload) SetParse(parse string) error {
+	if err := validateParse(parse); err != nil {
+		return err
+	}
+	payload.Parse = parse
+	return nil
+}
+
+// SetStyle sets the button style, rejecting anything Slack would silently
+// ignore.
+func (action *Action) SetStyle(style string) error {
+	if err := validateStyle(style); err != nil {
+		return err
+	}
+	action.Style = style
+	return nil
+}
+
+// ErrBlocksWithAttachments is a warning from Warnings that a payload has
+// both top-level blocks and attachments, which Slack renders with the blocks
+// and attachments disconnected. Put blocks inside an attachment, e.g. with
+// NewBlockAttachment, to show them with a colored bar instead.
+var ErrBlocksWithAttachments = errors.New("Payload has both blocks and attachments, use NewBlockAttachment for colored blocks")
+
+// Validate reports the first problem found in payload that Slack would
+// reject or render poorly.
+func (payload *Payload) Validate() error {
+	if err := validateParse(payload.Parse); err != nil {
+		return err
+	}
+
+	for i := range payload.Attachments {
+		if err := payload.Attachments[i].Validate(); err != nil {
+			return fmt.Errorf("Attachment %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Warnings lists things in payload that Slack accepts but probably won't
+// render the way they were meant. Unlike Validate's problems, these don't
+// stop a payload being sent.
+func (payload *Payload) Warnings() []error {
+	var warnings []error
+
+	if len(payload.Blocks) > 0 && len(payload.Attachments) > 0 {
+		warnings = append(warnings, ErrBlocksWithAttachments)
+	}
+
+	return warnings
+}
+
+func (attachment *Attachment) Validate() error {
+	if len(attachment.Fields) > MaxFieldsPerAttachment {
+		return fmt.Errorf("Too many fields: %d (max %d)", len(attachment.Fields), MaxFieldsPerAttachment)
+	}
+
+	if len(attachment.Actions) > MaxActionsPerAttachment {
+		return fmt.Errorf("Too many actions: %d (max %d)", len(attachment.Actions), MaxActionsPerAttachment)
+	}
+
+	if attachment.Color != nil {
+		if err := validateColor(*attachment.Color); err != nil {
+			return err
+		}
+	}
+
+	if attachment.FooterIcon != nil && (attachment.Footer == nil || *attachment.Footer == "") {
+		return fmt.Errorf("Footer icon is only shown when footer is set, use SetFooterIcon")
+	}
+
+	for i, action := range attachment.Actions {
+		if err := validateStyle(action.Style); err != nil {
+			return fmt.Errorf("Action %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateColor accepts Slack's named colors and #rgb or #rrggbb hex values.
+func validateColor(color string) error {
+	switch color {
+	case "", "good", "warning", "danger":
+		return nil
+	}
+
+	hex := strings.TrimPrefix(color, "#")
+	if hex != color && (len(hex) == 3 || len(hex) == 6) && strings.Trim(strings.ToLower(hex), "0123456789abcdef") == "" {
+		return nil
+	}
+
+	return fmt.Errorf("Invalid color %q, must be good, warning, danger or a hex value", color)
+}
+
+func validateStyle(style string) error {
+	switch style {
+	case "", StyleDefault, StylePrimary, StyleDanger:
+		return nil
+	}
+	return fmt.Errorf("Invalid style %q, must be one of %s, %s or %s", style, StyleDefault, StylePrimary, StyleDanger)
+}
+
+func validateParse(parse string) error {
+	switch parse {
+	case "", ParseFull, ParseNone:
+		return nil
+	}
+	return fmt.Errorf("Invalid parse %q, must be %s or %s", parse, ParseFull, ParseNone)
+}
+
+// ValidateWebhookURL checks that webhookUrl looks like a Slack incoming
+// webhook, catching the usual copy and paste mistakes.
+func ValidateWebhookURL(webhookUrl string) error {
+	if strings.TrimSpace(webhookUrl) != webhookUrl {
+		return fmt.Errorf("Webhook URL has leading or trailing whitespace")
+	}
+
+	parsed, err := url.Parse(webhookUrl)
+	if err != nil {
+		return fmt.Errorf("Invalid webhook URL: %w", err)
+	}
+
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("Webhook URL must use https, got %q", parsed.Scheme)
+	}
+
+	if parsed.Host != "hooks.slack.com" {
+		return fmt.Errorf("Webhook URL host must be hooks.slack.com, got %q", parsed.Host)
+	}
+
+	token := strings.TrimPrefix(parsed.Path, "/services/")
+	if token == parsed.Path || len(strings.Split(token, "/")) != 3 {
+		return fmt.Errorf("Webhook URL path must look like /services/T000/B000/XXXX, got %q", parsed.Path)
+	}
+
+	return nil
+}
+
+// WithQueryParam returns webhookUrl with key=value added to its query,
+// escaping both and keeping any parameters already there as they are.
+// Send uses URLs exactly as given, so this is safe for gateways that need
+// extra parameters. webhookUrl is returned unchanged if it can't be parsed.
+func WithQueryParam(webhookUrl, key, value string) string {
+	parsed, err := url.Parse(webhookUrl)
+	if err != nil {
+		return webhookUrl
+	}
+
+	param := url.QueryEscape(key) + "=" + url.QueryEscape(value)
+	if parsed.RawQuery == "" {
+		parsed.RawQuery = param
+	} else {
+		parsed.RawQuery += "&" + param
+	}
+
+	return parsed.String()
+}
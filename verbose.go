@@ -0,0 +1,54 @@
+package slack
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SendVerbose is Send that logs every request and response in full,
+// for investigating a failing send. The webhook token, the last segment of
+// the URL path, and any Authorization header are redacted.
+func SendVerbose(webhookUrl string, payload Payload) []error {
+	_, errs := send(webhookUrl, payload, sendOptions{verbose: true})
+	return errs
+}
+
+func logVerboseRequest(req *http.Request, attempt int, body []byte) {
+	logger.Printf("Slack request (attempt %d): %s %s\n%s\n%s", attempt, req.Method, redactURL(req.URL), formatHeader(req.Header), body)
+}
+
+func logVerboseResponse(resp *http.Response, body []byte) {
+	logger.Printf("Slack response: %d\n%s\n%s", resp.StatusCode, formatHeader(resp.Header), body)
+}
+
+// redactURL hides the last path segment, which holds the secret token in
+// webhook URLs.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	if i := strings.LastIndex(redacted.Path, "/"); i >= 0 && i < len(redacted.Path)-1 {
+		redacted.Path = redacted.Path[:i+1] + "REDACTED"
+		redacted.RawPath = ""
+	}
+	return redacted.String()
+}
+
+func formatHeader(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if http.CanonicalHeaderKey(key) == "Authorization" {
+			value = "REDACTED"
+		}
+		lines = append(lines, key+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
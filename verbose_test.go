@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/h2non/gock"
+)
+
+type bufferLogger struct {
+	lines []string
+}
+
+func (l *bufferLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestSendVerbose(t *testing.T) {
+	defer gock.Off()
+	disableSleep(t)
+
+	gock.New("https://hooks.slack.com").
+		Post("/services/T000/B000/secret-token").
+		Reply(200).
+		BodyString("ok")
+
+	gock.DisableNetworking()
+
+	log := &bufferLogger{}
+	SetLogger(log)
+	defer SetLogger(nil)
+
+	errs := SendVerbose("https://hooks.slack.com/services/T000/B000/secret-token", Payload{Text: "Hello"})
+	if len(errs) > 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	output := strings.Join(log.lines, "\n")
+	if strings.Contains(output, "secret-token") {
+		t.Errorf("Expected the token to be redacted, got:\n%s", output)
+	}
+	for _, expected := range []string{"/services/T000/B000/REDACTED", `"text":"Hello"`, "Slack response: 200"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", expected, output)
+		}
+	}
+}